@@ -0,0 +1,247 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "sync"
+  "time"
+  "context"
+  "net/url"
+)
+
+// BatchOptions specifies settings for scraping many URLs concurrently.
+type BatchOptions struct {
+  // The options used to scrape each URL.
+  ScrapeOptions ScrapeOptions
+  // The number of URLs scraped concurrently. Defaults to 1.
+  Workers int
+  // The maximum number of concurrent requests to a single host. If 0 then only Workers limits concurrency.
+  PerHostConcurrency int
+  // The minimum delay between the start of consecutive requests to a single host.
+  PerHostDelay time.Duration
+}
+
+// BatchResult is the outcome of scraping a single URL in a batch.
+type BatchResult struct {
+  // The URL that was scraped.
+  Url string
+  // The scrape results. Nil if Err is set.
+  Results []interface{}
+  // The error encountered while fetching or scraping the URL, if any. Responses with a status other
+  // than 2xx fail with a *StatusError.
+  Err error
+}
+
+/*
+ScrapeBatch scrapes every URL received from urls according to the iterator and data model specified, using
+opts.Workers goroutines. Results are sent on the returned channel as each URL completes, so they are not
+in the order the URLs were received. The returned channel is closed once urls is closed and every URL has
+been scraped, or once ctx is done. In-flight requests are cancelled when ctx is done.
+
+Requests to the same host are throttled according to opts.PerHostConcurrency and opts.PerHostDelay.
+
+Example:
+
+    urls := make(chan string)
+    go func () {
+      for _,id := range(appIds) {
+        urls <- "http://store.steampowered.com/app/" + id + "/"
+      }
+      close(urls)
+    }()
+
+    for result := range(dtoo.ScrapeBatch(ctx, urls, ".game", model, dtoo.BatchOptions{Workers: 8, PerHostDelay: time.Second})) {
+      if result.Err != nil {
+        log.Println(result.Url, result.Err)
+      }
+    }
+*/
+func ScrapeBatch(ctx context.Context, urls <-chan string, iterator string, model interface{}, opts BatchOptions) <-chan BatchResult {
+  out := make(chan BatchResult)
+  gate := newHostGate(opts.PerHostConcurrency, opts.PerHostDelay)
+  workers := opts.Workers
+
+  if workers < 1 {
+    workers = 1
+  }
+
+  var wg sync.WaitGroup
+  wg.Add(workers)
+
+  for w := 0; w < workers; w++ {
+    go func () {
+      defer wg.Done()
+
+      for {
+        select {
+          case <-ctx.Done():
+            return
+          case rawurl, ok := <-urls:
+            // Both cases may be ready at once so don't start work after ctx is done.
+            if !ok || ctx.Err() != nil {
+              return
+            }
+
+            result := scrapeBatchUrl(ctx, gate, rawurl, iterator, model, opts.ScrapeOptions)
+
+            select {
+              case out <- result:
+              case <-ctx.Done():
+                return
+            }
+        }
+      }
+    }()
+  }
+
+  go func () {
+    wg.Wait()
+    close(out)
+  }()
+
+  return out
+}
+
+// ScrapeUrls scrapes every URL in urls according to the iterator and data model specified, using
+// opts.Workers goroutines, and returns the results in the same order as urls. URLs that were not
+// scraped because ctx is done have their Err set to the context's error.
+//
+// Example:
+//
+//    results := dtoo.ScrapeUrls(context.Background(), urls, ".game", model, dtoo.BatchOptions{Workers: 8})
+func ScrapeUrls(ctx context.Context, urls []string, iterator string, model interface{}, opts BatchOptions) []BatchResult {
+  results := make([]BatchResult, len(urls))
+  indexes := make(map[string][]int)
+  in := make(chan string)
+
+  for i,rawurl := range(urls) {
+    indexes[rawurl] = append(indexes[rawurl], i)
+  }
+
+  go func () {
+    defer close(in)
+
+    for _,rawurl := range(urls) {
+      select {
+        case in <- rawurl:
+        case <-ctx.Done():
+          return
+      }
+    }
+  }()
+
+  done := make([]bool, len(urls))
+
+  for result := range(ScrapeBatch(ctx, in, iterator, model, opts)) {
+    // Duplicate URLs are scraped once per occurrence; fill the first unfilled slot.
+    for _,i := range(indexes[result.Url]) {
+      if !done[i] {
+        results[i] = result
+        done[i] = true
+        break
+      }
+    }
+  }
+
+  for i,rawurl := range(urls) {
+    if !done[i] {
+      results[i] = BatchResult{Url: rawurl, Err: ctx.Err()}
+    }
+  }
+
+  return results
+}
+
+func scrapeBatchUrl(ctx context.Context, gate *hostGate, rawurl string, iterator string, model interface{}, opts ScrapeOptions) BatchResult {
+  result := BatchResult{Url: rawurl}
+  host := rawurl
+
+  if u,err := url.Parse(rawurl); err == nil {
+    host = u.Host
+  }
+
+  if release,err := gate.acquire(ctx, host); err == nil {
+    result.Results, result.Err = ScrapeFromUrlWithContext(ctx, iterator, model, rawurl, opts)
+    release()
+  } else {
+    result.Err = err
+  }
+
+  return result
+}
+
+// hostGate enforces per-host politeness by limiting concurrent requests and spacing out request starts.
+type hostGate struct {
+  mu sync.Mutex
+  hosts map[string]*hostState
+  concurrency int
+  delay time.Duration
+}
+
+type hostState struct {
+  // Holds a token for every in-flight request. Nil if concurrency is unlimited.
+  slots chan struct{}
+  // The earliest time the next request to the host may start.
+  next time.Time
+}
+
+func newHostGate(concurrency int, delay time.Duration) *hostGate {
+  return &hostGate{hosts: make(map[string]*hostState), concurrency: concurrency, delay: delay}
+}
+
+// acquire blocks until a request to host may start. The returned func must be called once the request completes.
+func (g *hostGate) acquire(ctx context.Context, host string) (func (), error) {
+  g.mu.Lock()
+  h, exists := g.hosts[host]
+
+  if !exists {
+    h = &hostState{}
+
+    if g.concurrency > 0 {
+      h.slots = make(chan struct{}, g.concurrency)
+    }
+
+    g.hosts[host] = h
+  }
+  g.mu.Unlock()
+
+  release := func () {}
+
+  if h.slots != nil {
+    select {
+      case h.slots <- struct{}{}:
+        release = func () { <-h.slots }
+      case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+  }
+
+  if g.delay > 0 {
+    g.mu.Lock()
+    now := time.Now()
+    start := h.next
+
+    if start.Before(now) {
+      start = now
+    }
+
+    h.next = start.Add(g.delay)
+    g.mu.Unlock()
+
+    if wait := start.Sub(now); wait > 0 {
+      timer := time.NewTimer(wait)
+      defer timer.Stop()
+
+      select {
+        case <-timer.C:
+        case <-ctx.Done():
+          release()
+          return nil, ctx.Err()
+      }
+    }
+  }
+
+  return release, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "fmt"
+  "io"
+  "sync"
+  "time"
+  "context"
+  "testing"
+  "net/http"
+  "net/http/httptest"
+)
+
+func TestScrapeUrls(t *testing.T) {
+  var mu sync.Mutex
+  active, maxActive := 0, 0
+
+  server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    mu.Lock()
+    active++
+    if active > maxActive {
+      maxActive = active
+    }
+    mu.Unlock()
+
+    time.Sleep(5 * time.Millisecond)
+
+    if r.URL.Path == "/missing" {
+      w.WriteHeader(http.StatusNotFound)
+    } else {
+      io.WriteString(w, "<h1>" + r.URL.Path + "</h1>")
+    }
+
+    mu.Lock()
+    active--
+    mu.Unlock()
+  }))
+  defer server.Close()
+
+  urls := make([]string, 0)
+  for i := 0; i < 10; i++ {
+    urls = append(urls, fmt.Sprintf("%v/app/%v", server.URL, i))
+  }
+
+  results := ScrapeUrls(context.Background(), urls, "h1", "text", BatchOptions{Workers: 4, PerHostConcurrency: 2})
+
+  if len(results) != len(urls) {
+    t.Fatalf("result count invalid: expected %v got %v", len(urls), len(results))
+  }
+
+  for i,result := range(results) {
+    if result.Err != nil {
+      t.Fatal(result.Err)
+    }
+    if result.Url != urls[i] {
+      t.Fatalf("invalid result order: expected %v got %v", urls[i], result.Url)
+    }
+    if expected := fmt.Sprintf("/app/%v", i); len(result.Results) != 1 || result.Results[0] != expected {
+      t.Fatalf("invalid result: expected %v got %v", expected, result.Results)
+    }
+  }
+
+  if maxActive > 2 {
+    t.Fatalf("per-host concurrency exceeded: expected at most %v got %v", 2, maxActive)
+  }
+
+  // Error pages are reported as failures rather than scraped.
+  missing := ScrapeUrls(context.Background(), []string{server.URL + "/missing"}, "h1", "text", BatchOptions{})
+  statusErr, ok := missing[0].Err.(*StatusError)
+
+  if !ok || statusErr.StatusCode != http.StatusNotFound || statusErr.Url != server.URL + "/missing" || missing[0].Results != nil {
+    t.Fatalf("expected a 404 StatusError got %v (%v)", missing[0].Err, missing[0].Results)
+  }
+}
+
+func TestScrapeBatchPerHostDelay(t *testing.T) {
+  server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    io.WriteString(w, "<h1>ok</h1>")
+  }))
+  defer server.Close()
+
+  urls := make(chan string, 3)
+  for i := 0; i < 3; i++ {
+    urls <- server.URL
+  }
+  close(urls)
+
+  start := time.Now()
+  count := 0
+
+  for result := range(ScrapeBatch(context.Background(), urls, "h1", "text", BatchOptions{Workers: 3, PerHostDelay: 20 * time.Millisecond})) {
+    if result.Err != nil {
+      t.Fatal(result.Err)
+    }
+    count++
+  }
+
+  if count != 3 {
+    t.Fatalf("result count invalid: expected %v got %v", 3, count)
+  }
+  if elapsed := time.Since(start); elapsed < 40 * time.Millisecond {
+    t.Fatalf("per-host delay not honored: expected at least %v got %v", 40 * time.Millisecond, elapsed)
+  }
+}
+
+func TestScrapeUrlsCancelled(t *testing.T) {
+  server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    io.WriteString(w, "<h1>ok</h1>")
+  }))
+  defer server.Close()
+
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+
+  for _,result := range(ScrapeUrls(ctx, []string{server.URL, server.URL}, "h1", "text", BatchOptions{})) {
+    if result.Err != context.Canceled {
+      t.Fatalf("invalid error: expected %v got %v", context.Canceled, result.Err)
+    }
+  }
+}
@@ -0,0 +1,315 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "bufio"
+  "bytes"
+  "errors"
+  "strings"
+  "strconv"
+  "time"
+  "io/ioutil"
+  "crypto/sha1"
+  "encoding/hex"
+  "path/filepath"
+  "net/http"
+  "net/http/httputil"
+  "github.com/dschnare/dtoo/internal/fsutil"
+)
+
+// ErrCacheMiss is returned by CacheTransport in offline mode when a request has no cached response.
+var ErrCacheMiss = errors.New("Cache miss")
+
+/*
+CacheTransport is an http.RoundTripper that caches GET responses on disk so repeat scrapes of the same
+page don't hit the network. Cached responses are served while they are fresh according to their
+Cache-Control and Expires headers (or TTL if neither is present). Stale responses that have an ETag or
+Last-Modified header are revalidated with a conditional request.
+
+A response with a Vary header is only served for requests with the same values of the headers it names
+as the request it was cached for. A no-store response is never cached and evicts the cached response of
+its URL.
+
+Example:
+
+    client := &http.Client{Transport: &dtoo.CacheTransport{Dir: ".dtoo-cache", TTL: time.Hour}}
+    dtoo.ScrapeFromUrlWithOptions(".post", "id", url, dtoo.ScrapeOptions{Client: client})
+*/
+type CacheTransport struct {
+  // The directory cached responses are stored in. Created if it does not exist.
+  Dir string
+  // How long a response is fresh for when it specifies neither a max-age nor an Expires header.
+  // If 0 then such responses are revalidated on every request.
+  TTL time.Duration
+  // If true then the network is never used. Cached responses are served regardless of their
+  // freshness and requests without a cached response, including every request that isn't a GET, fail
+  // with ErrCacheMiss.
+  Offline bool
+  // The transport used to make requests. Defaults to http.DefaultTransport.
+  Transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+  // Only GET responses are cached so nothing else can be served offline.
+  if req.Method != "GET" {
+    if t.Offline {
+      return nil, ErrCacheMiss
+    }
+
+    return t.transport().RoundTrip(req)
+  }
+
+  path := t.path(req)
+  cached, body, stored := readCacheEntry(path, req)
+
+  // A cached response for other values of the headers it varies on is of no use.
+  if cached != nil && varies(cached.Header, req) {
+    cached = nil
+  }
+
+  if cached != nil {
+    stripVaryValues(cached.Header)
+  }
+
+  if cached != nil {
+    if t.Offline || time.Since(stored) < freshnessLifetime(cached.Header, t.TTL) {
+      return cached, nil
+    }
+  } else if t.Offline {
+    return nil, ErrCacheMiss
+  }
+
+  if cached != nil {
+    req = req.Clone(req.Context())
+
+    if etag := cached.Header.Get("ETag"); etag != EMPTYSTRING {
+      req.Header.Set("If-None-Match", etag)
+    }
+    if lastModified := cached.Header.Get("Last-Modified"); lastModified != EMPTYSTRING {
+      req.Header.Set("If-Modified-Since", lastModified)
+    }
+  }
+
+  resp, err := t.transport().RoundTrip(req)
+
+  if err != nil {
+    return nil, err
+  }
+
+  if cached != nil && resp.StatusCode == http.StatusNotModified {
+    resp.Body.Close()
+
+    for _,key := range([]string{"Cache-Control", "Date", "ETag", "Expires", "Last-Modified"}) {
+      if value := resp.Header.Get(key); value != EMPTYSTRING {
+        cached.Header.Set(key, value)
+      }
+    }
+
+    // Failing to refresh the entry doesn't fail the request, the response is still good.
+    writeCacheEntry(path, req, cached, body)
+    return cached, nil
+  }
+
+  // The cached response must not outlive a response that may not be stored.
+  if !isCacheable(resp.Header) {
+    os.Remove(path)
+  }
+
+  if resp.StatusCode == http.StatusOK && isCacheable(resp.Header) {
+    defer resp.Body.Close()
+
+    if body,err = ioutil.ReadAll(resp.Body); err != nil {
+      return nil, err
+    }
+
+    // As above, a response that can't be cached is still returned.
+    if err = os.MkdirAll(t.Dir, 0755); err == nil {
+      writeCacheEntry(path, req, resp, body)
+    } else {
+      resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+    }
+
+    return resp, nil
+  }
+
+  return resp, nil
+}
+
+func (t *CacheTransport) transport() http.RoundTripper {
+  if t.Transport != nil {
+    return t.Transport
+  }
+
+  return http.DefaultTransport
+}
+
+func (t *CacheTransport) path(req *http.Request) string {
+  sum := sha1.Sum([]byte(req.URL.String()))
+  return filepath.Join(t.Dir, hex.EncodeToString(sum[:]))
+}
+
+// readCacheEntry reads the cached response for req and the time it was stored.
+// Returns a nil response if there is no usable entry.
+func readCacheEntry(path string, req *http.Request) (*http.Response, []byte, time.Time) {
+  var stored time.Time
+
+  if info,err := os.Stat(path); err == nil {
+    stored = info.ModTime()
+  } else {
+    return nil, nil, stored
+  }
+
+  if dump,err := ioutil.ReadFile(path); err == nil {
+    if resp,err := http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req); err == nil {
+      defer resp.Body.Close()
+
+      if body,err := ioutil.ReadAll(resp.Body); err == nil {
+        resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+        return resp, body, stored
+      }
+    }
+  }
+
+  return nil, nil, stored
+}
+
+// writeCacheEntry stores resp to req with the specified body at path, along with the values of the
+// headers of req that resp varies on. The body of resp is replaced so it can still be read.
+func writeCacheEntry(path string, req *http.Request, resp *http.Response, body []byte) error {
+  resp.ContentLength = int64(len(body))
+  resp.TransferEncoding = nil
+  resp.Header.Del("Transfer-Encoding")
+  resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+  entry := *resp
+  entry.Header = resp.Header.Clone()
+  entry.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+  for _,name := range(varyNames(resp.Header)) {
+    entry.Header.Set(varyValuePrefix + name, varyValue(req, name))
+  }
+
+  dump, err := httputil.DumpResponse(&entry, true)
+  resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+  if err != nil {
+    return err
+  }
+
+  // Concurrent readers never see a partial entry.
+  return fsutil.WriteFileAtomic(path, dump, 0644)
+}
+
+// cacheControl parses the directives of a Cache-Control header.
+func cacheControl(h http.Header) map[string]string {
+  directives := map[string]string{}
+
+  for _,part := range(strings.Split(h.Get("Cache-Control"), ",")) {
+    if part = strings.TrimSpace(part); part != EMPTYSTRING {
+      if i := strings.Index(part, "="); i >= 0 {
+        directives[strings.ToLower(part[:i])] = strings.Trim(part[i + 1:], "\"")
+      } else {
+        directives[strings.ToLower(part)] = EMPTYSTRING
+      }
+    }
+  }
+
+  return directives
+}
+
+// isCacheable reports whether a response with the specified headers may be stored. A response that
+// varies on "*" never matches a later request so it isn't stored either.
+func isCacheable(h http.Header) bool {
+  _, noStore := cacheControl(h)["no-store"]
+
+  for _,name := range(varyNames(h)) {
+    if name == "*" {
+      return false
+    }
+  }
+
+  return !noStore
+}
+
+// varyValuePrefix prefixes the headers of a cache entry that hold the values of the request headers
+// the response varies on.
+const varyValuePrefix = "X-Dtoo-Vary-"
+
+// varyNames returns the canonical names of the request headers a response with the specified headers
+// varies on.
+func varyNames(h http.Header) []string {
+  names := make([]string, 0)
+
+  for _,value := range(h.Values("Vary")) {
+    for _,name := range(strings.Split(value, ",")) {
+      if name = strings.TrimSpace(name); name != EMPTYSTRING {
+        names = append(names, http.CanonicalHeaderKey(name))
+      }
+    }
+  }
+
+  return names
+}
+
+func varyValue(req *http.Request, name string) string {
+  return strings.Join(req.Header.Values(name), ", ")
+}
+
+// varies reports whether the cached response with the specified headers was cached for other values of
+// the headers of req it varies on.
+func varies(h http.Header, req *http.Request) bool {
+  for _,name := range(varyNames(h)) {
+    if name == "*" || h.Get(varyValuePrefix + name) != varyValue(req, name) {
+      return true
+    }
+  }
+
+  return false
+}
+
+// stripVaryValues removes the headers of a cache entry added by writeCacheEntry.
+func stripVaryValues(h http.Header) {
+  for name := range(h) {
+    if strings.HasPrefix(name, varyValuePrefix) {
+      delete(h, name)
+    }
+  }
+}
+
+// freshnessLifetime returns how long a response with the specified headers is fresh for.
+func freshnessLifetime(h http.Header, ttl time.Duration) time.Duration {
+  directives := cacheControl(h)
+
+  if _,noCache := directives["no-cache"]; noCache {
+    return 0
+  }
+
+  if maxAge,ok := directives["max-age"]; ok {
+    if seconds,err := strconv.Atoi(maxAge); err == nil {
+      return time.Duration(seconds) * time.Second
+    }
+
+    return 0
+  }
+
+  if expires := h.Get("Expires"); expires != EMPTYSTRING {
+    if expiresAt,err := http.ParseTime(expires); err == nil {
+      date := time.Now()
+
+      if d,err := http.ParseTime(h.Get("Date")); err == nil {
+        date = d
+      }
+
+      return expiresAt.Sub(date)
+    }
+
+    return 0
+  }
+
+  return ttl
+}
@@ -0,0 +1,209 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "io"
+  "time"
+  "errors"
+  "testing"
+  "io/ioutil"
+  "net/http"
+  "path/filepath"
+  "net/http/httptest"
+)
+
+func newCacheTestServer(hits *int, cacheControl string) *httptest.Server {
+  return httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    *hits++
+
+    if cacheControl != EMPTYSTRING {
+      w.Header().Set("Cache-Control", cacheControl)
+    }
+    w.Header().Set("ETag", `"v1"`)
+
+    if r.Header.Get("If-None-Match") == `"v1"` {
+      w.WriteHeader(http.StatusNotModified)
+      return
+    }
+
+    io.WriteString(w, `<ul><li id="a">A</li><li id="b">B</li></ul>`)
+  }))
+}
+
+func scrapeIds(t *testing.T, url string, transport *CacheTransport) []interface{} {
+  ids, err := ScrapeFromUrlWithOptions("li", "id", url, ScrapeOptions{Client: &http.Client{Transport: transport}})
+
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+    t.Fatalf("invalid ids retrieved: expected %v got %v", []string{"a", "b"}, ids)
+  }
+
+  return ids
+}
+
+func TestCacheTransportServesFreshResponses(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-cache")
+  defer os.RemoveAll(dir)
+
+  hits := 0
+  server := newCacheTestServer(&hits, "max-age=60")
+  defer server.Close()
+
+  transport := &CacheTransport{Dir: dir}
+  scrapeIds(t, server.URL, transport)
+  scrapeIds(t, server.URL, transport)
+
+  if hits != 1 {
+    t.Fatalf("invalid request count: expected %v got %v", 1, hits)
+  }
+}
+
+func TestCacheTransportRevalidatesStaleResponses(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-cache")
+  defer os.RemoveAll(dir)
+
+  hits := 0
+  server := newCacheTestServer(&hits, EMPTYSTRING)
+  defer server.Close()
+
+  transport := &CacheTransport{Dir: dir}
+  scrapeIds(t, server.URL, transport)
+  // The second request is answered with 304 Not Modified and served from the cache.
+  scrapeIds(t, server.URL, transport)
+
+  if hits != 2 {
+    t.Fatalf("invalid request count: expected %v got %v", 2, hits)
+  }
+
+  transport.TTL = time.Hour
+  scrapeIds(t, server.URL, transport)
+
+  if hits != 2 {
+    t.Fatalf("invalid request count: expected %v got %v", 2, hits)
+  }
+}
+
+func TestCacheTransportOffline(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-cache")
+  defer os.RemoveAll(dir)
+
+  hits := 0
+  server := newCacheTestServer(&hits, "no-cache")
+  defer server.Close()
+
+  offline := &CacheTransport{Dir: dir, Offline: true}
+
+  if _,err := ScrapeFromUrlWithOptions("li", "id", server.URL, ScrapeOptions{Client: &http.Client{Transport: offline}}); err == nil {
+    t.Fatalf("expected a cache miss in offline mode")
+  }
+
+  scrapeIds(t, server.URL, &CacheTransport{Dir: dir})
+  scrapeIds(t, server.URL, offline)
+
+  // Offline mode never sends requests that can't be cached.
+  if resp,err := (&http.Client{Transport: offline}).Post(server.URL, "text/plain", nil); err == nil || !errors.Is(err, ErrCacheMiss) {
+    t.Fatalf("expected a cache miss for a POST in offline mode got %v (%v)", resp, err)
+  }
+
+  if hits != 1 {
+    t.Fatalf("invalid request count: expected %v got %v", 1, hits)
+  }
+}
+
+func TestCacheTransportWriteFailure(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-cache")
+  defer os.RemoveAll(dir)
+
+  hits := 0
+  server := newCacheTestServer(&hits, "max-age=3600")
+  defer server.Close()
+
+  // The cache directory can't be created under a file, which doesn't fail the scrape.
+  ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0644)
+  scrapeIds(t, server.URL, &CacheTransport{Dir: filepath.Join(dir, "file", "cache")})
+}
+
+func TestCacheTransportNoStore(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-cache")
+  defer os.RemoveAll(dir)
+
+  hits := 0
+  server := newCacheTestServer(&hits, "no-store")
+  defer server.Close()
+
+  transport := &CacheTransport{Dir: dir, TTL: time.Hour}
+  scrapeIds(t, server.URL, transport)
+  scrapeIds(t, server.URL, transport)
+
+  if hits != 2 {
+    t.Fatalf("invalid request count: expected %v got %v", 2, hits)
+  }
+
+  // A no-store response evicts the response cached before it, so it isn't served offline either.
+  noStore := true
+  server = httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    if noStore {
+      w.Header().Set("Cache-Control", "no-store")
+    } else {
+      w.Header().Set("Cache-Control", "no-cache")
+    }
+
+    io.WriteString(w, `<ul><li id="a">A</li><li id="b">B</li></ul>`)
+  }))
+  defer server.Close()
+
+  noStore = false
+  scrapeIds(t, server.URL, transport)
+  noStore = true
+  scrapeIds(t, server.URL, transport)
+
+  if _,err := ScrapeFromUrlWithOptions("li", "id", server.URL, ScrapeOptions{Client: &http.Client{Transport: &CacheTransport{Dir: dir, Offline: true}}}); !errors.Is(err, ErrCacheMiss) {
+    t.Fatalf("expected a cache miss got %v", err)
+  }
+}
+
+func TestCacheTransportVary(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-cache")
+  defer os.RemoveAll(dir)
+
+  hits := 0
+  server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    hits++
+    w.Header().Set("Cache-Control", "max-age=3600")
+    w.Header().Set("Vary", "Accept-Language")
+    io.WriteString(w, "<p>" + r.Header.Get("Accept-Language") + "</p>")
+  }))
+  defer server.Close()
+
+  client := &http.Client{Transport: &CacheTransport{Dir: dir}}
+  get := func (language string) string {
+    req, _ := http.NewRequest("GET", server.URL, nil)
+    req.Header.Set("Accept-Language", language)
+    resp, err := client.Do(req)
+
+    if err != nil {
+      t.Fatal(err)
+    }
+
+    defer resp.Body.Close()
+
+    if resp.Header.Get(varyValuePrefix + "Accept-Language") != EMPTYSTRING {
+      t.Fatalf("cache headers leaked into the response: %v", resp.Header)
+    }
+
+    body, _ := ioutil.ReadAll(resp.Body)
+    return string(body)
+  }
+
+  for _,test := range([]struct{ language string; hits int }{{"en", 1}, {"en", 1}, {"fr", 2}, {"fr", 2}, {"en", 3}}) {
+    if body := get(test.language); body != "<p>" + test.language + "</p>" || hits != test.hits {
+      t.Fatalf("invalid response for %v: %v after %v requests", test.language, body, hits)
+    }
+  }
+}
@@ -0,0 +1,104 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "io"
+  "mime"
+  "bytes"
+  "errors"
+  "strings"
+  "io/ioutil"
+  "unicode/utf8"
+  "golang.org/x/net/html"
+  "golang.org/x/net/html/charset"
+  "golang.org/x/text/encoding"
+  "github.com/PuerkitoBio/goquery"
+)
+
+// newDocument parses a goquery.Document from r after transcoding its content to UTF-8.
+// If label is empty then the encoding is detected from the BOM, the contentType
+// (i.e. the value of a Content-Type header) and any <meta charset> tag, in that order.
+// Otherwise label names the encoding to use and detection is skipped.
+func newDocument(r io.Reader, contentType string, label string) (*goquery.Document, error) {
+  content, err := ioutil.ReadAll(r)
+
+  if err != nil {
+    return nil, err
+  }
+
+  if enc,err := determineEncoding(content, contentType, label); err == nil {
+    return goquery.NewDocumentFromReader(enc.NewDecoder().Reader(bytes.NewReader(content)))
+  } else {
+    return nil, err
+  }
+}
+
+func determineEncoding(content []byte, contentType string, label string) (encoding.Encoding, error) {
+  if label != EMPTYSTRING {
+    if enc,_ := charset.Lookup(label); enc != nil {
+      return enc, nil
+    }
+
+    return nil, errors.New("Unsupported charset " + label)
+  }
+
+  enc, name, certain := charset.DetermineEncoding(content, contentType)
+
+  // When neither the BOM, the contentType nor a <meta> tag declares an encoding the HTML spec falls
+  // back to windows-1252 and only the first 1024 bytes are sniffed for UTF-8. A document that is valid
+  // UTF-8 throughout is far more likely to be UTF-8 than windows-1252 so prefer it. DetermineEncoding
+  // is never certain of a <meta> tag so look for one.
+  if !certain && name == "windows-1252" && !declaresCharset(content) && utf8.Valid(content) {
+    return encoding.Nop, nil
+  }
+
+  return enc, nil
+}
+
+// declaresCharset reports whether a <meta> tag within the first 1024 bytes of content declares a known
+// encoding, as the prescan of the HTML spec would find it.
+func declaresCharset(content []byte) bool {
+  if len(content) > 1024 {
+    content = content[:1024]
+  }
+
+  z := html.NewTokenizer(bytes.NewReader(content))
+
+  for {
+    switch z.Next() {
+      case html.ErrorToken:
+        return false
+      case html.StartTagToken, html.SelfClosingTagToken:
+        if name,hasAttr := z.TagName(); string(name) == "meta" && hasAttr {
+          var label, httpEquiv, contentAttr string
+
+          for more := true; more; {
+            var key, value []byte
+            key, value, more = z.TagAttr()
+
+            switch string(key) {
+              case "charset":
+                label = string(value)
+              case "http-equiv":
+                httpEquiv = strings.ToLower(string(value))
+              case "content":
+                contentAttr = string(value)
+            }
+          }
+
+          if label == EMPTYSTRING && httpEquiv == "content-type" {
+            if _,params,err := mime.ParseMediaType(contentAttr); err == nil {
+              label = params["charset"]
+            }
+          }
+
+          if enc,_ := charset.Lookup(label); enc != nil {
+            return true
+          }
+        }
+    }
+  }
+}
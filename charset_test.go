@@ -0,0 +1,78 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "bytes"
+  "strings"
+  "testing"
+  "golang.org/x/text/encoding/japanese"
+)
+
+func TestScrapeDetectsMetaCharset(t *testing.T) {
+  html := []byte("<html><head><meta charset=\"windows-1252\"></head><body><p>Caf\xe9 cr\xe8me</p></body></html>")
+
+  if items,err := ScrapeFromReader("p", "text", bytes.NewReader(html)); err == nil {
+    if len(items) != 1 || items[0] != "Café crème" {
+      t.Fatalf("invalid text retrieved: expected %v got %v", "Café crème", items)
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestScrapeKeepsDeclaredWindows1252(t *testing.T) {
+  // The bytes are valid UTF-8 but the document declares windows-1252, which is what it's decoded as.
+  for _,meta := range([]string{`<meta charset="windows-1252">`, `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">`}) {
+    html := []byte("<html><head>" + meta + "</head><body><p>Caf\xc3\xa9</p></body></html>")
+
+    if items,err := ScrapeFromReader("p", "text", bytes.NewReader(html)); err == nil {
+      if len(items) != 1 || items[0] != "CafÃ©" {
+        t.Fatalf("invalid text retrieved with %v: expected %v got %v", meta, "CafÃ©", items)
+      }
+    } else {
+      t.Fatal(err)
+    }
+  }
+
+  // Without a declaration a valid UTF-8 document is decoded as UTF-8, even past the bytes sniffed.
+  html := []byte("<html><body><!--" + strings.Repeat(" ", 1024) + "--><p>Caf\xc3\xa9</p></body></html>")
+
+  if items,err := ScrapeFromReader("p", "text", bytes.NewReader(html)); err != nil || len(items) != 1 || items[0] != "Café" {
+    t.Fatalf("invalid text retrieved: expected %v got %v (%v)", "Café", items, err)
+  }
+}
+
+func TestScrapeDetectsBOM(t *testing.T) {
+  html := []byte("\xef\xbb\xbf<html><head><meta charset=\"windows-1252\"></head><body><p>Café</p></body></html>")
+
+  if items,err := ScrapeFromReader("p", "text", bytes.NewReader(html)); err == nil {
+    if len(items) != 1 || items[0] != "Café" {
+      t.Fatalf("invalid text retrieved: expected %v got %v", "Café", items)
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestScrapeWithCharsetOverride(t *testing.T) {
+  sjis, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte("<p>日本語</p>"))
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if items,err := ScrapeFromReaderWithOptions("p", "text", bytes.NewReader(sjis), ScrapeOptions{Charset: "shift_jis"}); err == nil {
+    if len(items) != 1 || items[0] != "日本語" {
+      t.Fatalf("invalid text retrieved: expected %v got %v", "日本語", items)
+    }
+  } else {
+    t.Fatal(err)
+  }
+
+  if _,err := ScrapeFromReaderWithOptions("p", "text", bytes.NewReader(sjis), ScrapeOptions{Charset: "klingon"}); err == nil {
+    t.Fatalf("expected an error for an unsupported charset")
+  }
+}
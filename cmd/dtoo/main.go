@@ -0,0 +1,142 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+The dtoo command runs dtoo data models serialized as JSON.
+
+	dtoo serve [-addr :8080] [-models models.json] [-timeout 30s] [-max-body bytes] [-max-document 10485760] [-max-concurrent 16] [-allow-private]
+
+    dtoo jobs -config jobs.json [-run name]
+
+serve exposes scraping as a JSON HTTP API, see package server. The models file maps names to the
+iterator, model and options of the models preloaded into the server.
+
+jobs runs the jobs of a config on their schedules until interrupted, see package jobs. With -run it runs
+the named job once and exits.
+*/
+package main
+
+import (
+  "os"
+  "fmt"
+  "log"
+  "flag"
+  "context"
+  "net/http"
+  "os/signal"
+  "github.com/dschnare/dtoo/jobs"
+  "github.com/dschnare/dtoo/server"
+)
+
+const usage = `Usage: dtoo <command> [flags]
+
+Commands:
+  serve    serve the scraping API over HTTP
+  jobs     run scheduled scrape jobs
+`
+
+func main() {
+  if len(os.Args) < 2 {
+    fmt.Fprint(os.Stderr, usage)
+    os.Exit(2)
+  }
+
+  var err error
+
+  switch os.Args[1] {
+    case "serve":
+      err = serve(os.Args[2:])
+    case "jobs":
+      err = runJobs(os.Args[2:])
+    default:
+      fmt.Fprint(os.Stderr, usage)
+      os.Exit(2)
+  }
+
+  if err != nil {
+    log.Fatal(err)
+  }
+}
+
+func serve(args []string) error {
+  flags := flag.NewFlagSet("serve", flag.ExitOnError)
+  addr := flags.String("addr", ":8080", "the address to listen on")
+  models := flags.String("models", "", "a JSON file of named models to preload")
+  timeout := flags.Duration("timeout", server.DefaultTimeout, "the time a scrape may take")
+  maxBody := flags.Int64("max-body", 0, "the maximum size of a request body in bytes (default max-document plus 1 MiB)")
+  maxDocument := flags.Int64("max-document", server.DefaultMaxDocumentBytes, "the maximum size of a document in bytes")
+  maxConcurrent := flags.Int("max-concurrent", server.DefaultMaxConcurrent, "the maximum number of scrapes run at once")
+  allowPrivate := flags.Bool("allow-private", false, "allow fetching URLs on loopback, private and link-local addresses")
+  flags.Parse(args)
+
+  opts := server.Options{
+    Timeout: *timeout,
+    MaxBodyBytes: *maxBody,
+    MaxDocumentBytes: *maxDocument,
+    MaxConcurrent: *maxConcurrent,
+    AllowPrivateNetworks: *allowPrivate,
+  }
+
+  if *models != "" {
+    file, err := os.Open(*models)
+
+    if err != nil {
+      return err
+    }
+
+    opts.Models, err = server.LoadModels(file)
+    file.Close()
+
+    if err != nil {
+      return err
+    }
+  }
+
+  log.Printf("Listening on %v", *addr)
+  return http.ListenAndServe(*addr, server.New(opts))
+}
+
+func runJobs(args []string) error {
+  flags := flag.NewFlagSet("jobs", flag.ExitOnError)
+  configFile := flags.String("config", "jobs.json", "the JSON config of the jobs")
+  name := flags.String("run", "", "run the named job once and exit")
+  flags.Parse(args)
+
+  file, err := os.Open(*configFile)
+
+  if err != nil {
+    return err
+  }
+
+  config, err := jobs.LoadConfig(file)
+  file.Close()
+
+  if err != nil {
+    return err
+  }
+
+  runner, err := jobs.NewRunner(config)
+
+  if err != nil {
+    return err
+  }
+
+  ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+  defer stop()
+
+  if *name != "" {
+    return runner.RunJob(ctx, *name)
+  }
+
+  runner.OnRun = func (name string, err error) {
+    if err == nil {
+      log.Printf("%v: ok", name)
+    } else {
+      log.Printf("%v: %v", name, err)
+    }
+  }
+
+  log.Printf("Running %d jobs", len(config.Jobs))
+  return runner.Run(ctx)
+}
@@ -0,0 +1,225 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package columnar writes the results of dtoo scrapes in the columnar Parquet and Arrow IPC file formats for
+analytics pipelines.
+
+The schema is derived from the data model by Schema, so each key of a Model becomes a column, nested
+Models become struct columns and recursive scrapes, such as a list of genres, become list columns.
+
+  games, err := dtoo.ScrapeFromUrl(".search_result_row", model, url)
+  file, err := os.Create("games.parquet")
+  err = columnar.WriteParquet(file, model, games)
+*/
+package columnar
+
+import (
+  "io"
+  "fmt"
+  "strconv"
+  "strings"
+  "reflect"
+  "encoding/json"
+  "github.com/apache/arrow-go/v18/arrow"
+  "github.com/apache/arrow-go/v18/arrow/array"
+  "github.com/apache/arrow-go/v18/arrow/ipc"
+  "github.com/apache/arrow-go/v18/arrow/memory"
+  "github.com/apache/arrow-go/v18/parquet"
+  "github.com/apache/arrow-go/v18/parquet/compress"
+  "github.com/apache/arrow-go/v18/parquet/pqarrow"
+  "github.com/dschnare/dtoo"
+)
+
+// WriteParquet writes results to w as a Snappy compressed Parquet file with the schema derived from model.
+func WriteParquet(w io.Writer, model interface{}, results []interface{}) error {
+  record, err := Record(model, results)
+
+  if err != nil {
+    return err
+  }
+
+  defer record.Release()
+
+  props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+  fw, err := pqarrow.NewFileWriter(record.Schema(), w, props, pqarrow.DefaultWriterProps())
+
+  if err != nil {
+    return err
+  }
+
+  if err = fw.Write(record); err != nil {
+    fw.Close()
+    return err
+  }
+
+  return fw.Close()
+}
+
+// WriteArrow writes results to w as an Arrow IPC file with the schema derived from model.
+func WriteArrow(w io.Writer, model interface{}, results []interface{}) error {
+  record, err := Record(model, results)
+
+  if err != nil {
+    return err
+  }
+
+  defer record.Release()
+
+  fw, err := ipc.NewFileWriter(w, ipc.WithSchema(record.Schema()))
+
+  if err != nil {
+    return err
+  }
+
+  if err = fw.Write(record); err != nil {
+    fw.Close()
+    return err
+  }
+
+  return fw.Close()
+}
+
+// Record returns results as an Arrow record batch with the schema derived from model, for pipelines that
+// consume Arrow data directly. The caller must release it.
+//
+// Values are converted to the type of their column where possible, i.e. "90" is stored as 90 in an integer
+// column and anything can be stored in a string column. Returns an error if a value can't be converted.
+func Record(model interface{}, results []interface{}) (arrow.RecordBatch, error) {
+  schema, columns := schemaOf(model, results)
+  builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+  defer builder.Release()
+
+  for i,result := range(results) {
+    for j,f := range(schema.Fields()) {
+      value := result
+
+      if columns {
+        value = field(result, f.Name)
+      }
+
+      if err := appendValue(builder.Field(j), value); err != nil {
+        return nil, fmt.Errorf("[%d].%v: %v", i, f.Name, err)
+      }
+    }
+  }
+
+  return builder.NewRecordBatch(), nil
+}
+
+// appendValue appends value to b, converting it to the type of b.
+func appendValue(b array.Builder, value interface{}) error {
+  if value == nil {
+    b.AppendNull()
+    return nil
+  }
+
+  switch builder := b.(type) {
+    case *array.StringBuilder:
+      builder.Append(toString(value))
+    case *array.Int64Builder:
+      if i,err := toInt(value); err == nil {
+        builder.Append(i)
+      } else {
+        return err
+      }
+    case *array.Float64Builder:
+      if f,err := toFloat(value); err == nil {
+        builder.Append(f)
+      } else {
+        return err
+      }
+    case *array.BooleanBuilder:
+      if v,ok := value.(bool); ok {
+        builder.Append(v)
+      } else if v,err := strconv.ParseBool(strings.TrimSpace(toString(value))); err == nil {
+        builder.Append(v)
+      } else {
+        return fmt.Errorf("cannot store %T as a bool", value)
+      }
+    case *array.ListBuilder:
+      v := reflect.ValueOf(value)
+
+      if _,isModel := value.(dtoo.OrderedModel); isModel || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+        return fmt.Errorf("cannot store %T as a list", value)
+      }
+
+      builder.Append(true)
+
+      for i := 0; i < v.Len(); i++ {
+        if err := appendValue(builder.ValueBuilder(), v.Index(i).Interface()); err != nil {
+          return fmt.Errorf("[%d]: %v", i, err)
+        }
+      }
+    case *array.StructBuilder:
+      switch value.(type) {
+        case dtoo.Model, dtoo.OrderedModel, map[string]interface{}:
+        default:
+          return fmt.Errorf("cannot store %T as a struct", value)
+      }
+
+      builder.Append(true)
+      st := builder.Type().(*arrow.StructType)
+
+      for i,f := range(st.Fields()) {
+        if err := appendValue(builder.FieldBuilder(i), field(value, f.Name)); err != nil {
+          return fmt.Errorf("%v: %v", f.Name, err)
+        }
+      }
+    default:
+      return fmt.Errorf("unsupported column type %v", b.Type())
+  }
+
+  return nil
+}
+
+func toString(value interface{}) string {
+  switch v := value.(type) {
+    case string:
+      return v
+    case fmt.Stringer:
+      return v.String()
+  }
+
+  switch reflect.ValueOf(value).Kind() {
+    case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+      if data,err := json.Marshal(value); err == nil {
+        return string(data)
+      }
+  }
+
+  return fmt.Sprint(value)
+}
+
+func toInt(value interface{}) (int64, error) {
+  switch v := reflect.ValueOf(value); v.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      return v.Int(), nil
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      return int64(v.Uint()), nil
+    case reflect.String:
+      if i,err := strconv.ParseInt(strings.TrimSpace(v.String()), 10, 64); err == nil {
+        return i, nil
+      }
+  }
+
+  return 0, fmt.Errorf("cannot store %v as an integer", value)
+}
+
+func toFloat(value interface{}) (float64, error) {
+  switch v := reflect.ValueOf(value); v.Kind() {
+    case reflect.Float32, reflect.Float64:
+      return v.Float(), nil
+    case reflect.String:
+      if f,err := strconv.ParseFloat(strings.TrimSpace(v.String()), 64); err == nil {
+        return f, nil
+      }
+    default:
+      if i,err := toInt(value); err == nil {
+        return float64(i), nil
+      }
+  }
+
+  return 0, fmt.Errorf("cannot store %v as a number", value)
+}
@@ -0,0 +1,126 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package columnar
+
+import (
+  "bytes"
+  "context"
+  "testing"
+  "github.com/apache/arrow-go/v18/arrow/ipc"
+  "github.com/apache/arrow-go/v18/arrow/memory"
+  "github.com/apache/arrow-go/v18/parquet"
+  "github.com/apache/arrow-go/v18/parquet/pqarrow"
+  "github.com/PuerkitoBio/goquery"
+  "github.com/dschnare/dtoo"
+)
+
+const gamesHtml = `<ul>
+<li id="570"><h4>Dota 2</h4><span class="score">90</span><p>Action, Strategy</p><a href="/a">A</a><a href="/b">B</a></li>
+<li id="440"><h4>TF2</h4><span class="score">92</span><p>Action</p></li>
+</ul>`
+
+var gameModel = dtoo.Model{
+  "Id": "id",
+  "Name": dtoo.RetrieverModel{Sel: "h4", Method: "text"},
+  "Metascore": dtoo.RetrieverModel{Sel: ".score", Method: func (s *goquery.Selection) (interface{}, error) {
+    return len(s.Text()) * 45, nil
+  }},
+  "Links": dtoo.RetrieverModel{Scrape: dtoo.ScrapeObject{Iterator: "a", Data: dtoo.Model{"Href": "href"}}},
+  "Genres": dtoo.Computed{Deps: []string{"Name"}, Func: func (data dtoo.Model) (interface{}, error) {
+    if data["Name"] == "TF2" {
+      return []string{"Action"}, nil
+    }
+
+    return []string{"Action", "Strategy"}, nil
+  }},
+}
+
+func TestSchema(t *testing.T) {
+  games, err := dtoo.ScrapeFromString("li", gameModel, gamesHtml)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  expected := `schema:
+  fields: 5
+    - Genres: type=list<item: utf8, nullable>, nullable
+    - Id: type=utf8, nullable
+    - Links: type=list<item: struct<Href: utf8 nullable>, nullable>, nullable
+    - Metascore: type=int64, nullable
+    - Name: type=utf8, nullable`
+
+  if schema := Schema(gameModel, games).String(); schema != expected {
+    t.Fatalf("invalid schema: expected\n%v\ngot\n%v", expected, schema)
+  }
+
+  if schema := Schema("id", []interface{}{"1"}).String(); schema != "schema:\n  fields: 1\n    - value: type=utf8, nullable" {
+    t.Fatalf("invalid schema: %v", schema)
+  }
+}
+
+func TestWriteParquet(t *testing.T) {
+  games, err := dtoo.ScrapeFromString("li", gameModel, gamesHtml)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  buf := &bytes.Buffer{}
+
+  if err := WriteParquet(buf, gameModel, games); err != nil {
+    t.Fatal(err)
+  }
+
+  table, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), parquet.NewReaderProperties(memory.DefaultAllocator), pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer table.Release()
+
+  if table.NumRows() != 2 || table.NumCols() != 5 {
+    t.Fatalf("invalid table: %v rows and %v columns", table.NumRows(), table.NumCols())
+  }
+
+  genres := table.Column(0).Data().Chunk(0).String()
+
+  if genres != `[["Action" "Strategy"] ["Action"]]` {
+    t.Fatalf("invalid genres: %v", genres)
+  }
+}
+
+func TestWriteArrow(t *testing.T) {
+  results := []interface{}{dtoo.Model{"Score": "90"}, dtoo.Model{"Score": nil}, dtoo.Model{"Score": 1.5}}
+  model := dtoo.Model{"Score": func (s *goquery.Selection) (interface{}, error) { return nil, nil }}
+  buf := &bytes.Buffer{}
+
+  // Strings and numbers conflict so the column is stored as strings.
+  if err := WriteArrow(buf, model, results); err != nil {
+    t.Fatal(err)
+  }
+
+  reader, err := ipc.NewFileReader(bytes.NewReader(buf.Bytes()))
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer reader.Close()
+  record, err := reader.RecordBatch(0)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if scores := record.Column(0).String(); scores != `["90" (null) "1.5"]` {
+    t.Fatalf("invalid scores: %v", scores)
+  }
+
+  if _,err := Record(dtoo.Model{"Tags": dtoo.RetrieverModel{Scrape: dtoo.ScrapeObject{Iterator: "a", Data: "text"}}}, []interface{}{dtoo.Model{"Tags": "x"}}); err == nil || err.Error() != "[0].Tags: cannot store string as a list" {
+    t.Fatalf("invalid error: %v", err)
+  }
+}
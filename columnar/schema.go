@@ -0,0 +1,249 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package columnar
+
+import (
+  "sort"
+  "reflect"
+  "github.com/apache/arrow-go/v18/arrow"
+  "github.com/dschnare/dtoo"
+)
+
+/*
+Schema derives the Arrow schema of the results of a data model. Types are taken from the data model where
+it declares them: string models, attributes and the "text", "html" and "absUrl" methods are strings,
+Models and OrderedModels are structs and recursive scrapes are lists of their Data. The types of func
+retrievers, Computed keys and Switch models are inferred from the values in results, as is the type of
+any value the model does not declare.
+
+If the results are Models or OrderedModels then each of their keys is a column. Otherwise the results are
+stored in a single column named "value". Every column is nullable.
+*/
+func Schema(model interface{}, results []interface{}) *arrow.Schema {
+  schema, _ := schemaOf(model, results)
+  return schema
+}
+
+// schemaOf returns the schema of the results of model and whether the keys of the results are its columns.
+func schemaOf(model interface{}, results []interface{}) (*arrow.Schema, bool) {
+  t := typeOf(model, results)
+
+  if st,ok := t.(*arrow.StructType); ok {
+    return arrow.NewSchema(st.Fields(), nil), true
+  }
+
+  return arrow.NewSchema([]arrow.Field{{Name: "value", Type: t, Nullable: true}}, nil), false
+}
+
+// typeOf returns the type of the values extracted by model.
+func typeOf(model interface{}, values []interface{}) arrow.DataType {
+  switch m := model.(type) {
+    case string:
+      return arrow.BinaryTypes.String
+    case dtoo.RetrieverModel:
+      if _,isFunc := m.Method.(string); m.Attr == "" && m.Method != nil && !isFunc {
+        return inferType(values)
+      }
+
+      if m.Attr == "" && m.Method == nil && m.Scrape.Data != nil {
+        return arrow.ListOf(typeOf(m.Scrape.Data, elements(values)))
+      }
+
+      return arrow.BinaryTypes.String
+    case dtoo.Model:
+      keys := make([]string, 0, len(m))
+
+      for key := range(m) {
+        keys = append(keys, key)
+      }
+
+      sort.Strings(keys)
+      fields := make([]arrow.Field, len(keys))
+
+      for i,key := range(keys) {
+        fields[i] = arrow.Field{Name: key, Type: typeOf(m[key], fieldValues(values, key)), Nullable: true}
+      }
+
+      return arrow.StructOf(fields...)
+    case dtoo.OrderedModel:
+      fields := make([]arrow.Field, len(m))
+
+      for i,field := range(m) {
+        fields[i] = arrow.Field{Name: field.Key, Type: typeOf(field.Value, fieldValues(values, field.Key)), Nullable: true}
+      }
+
+      return arrow.StructOf(fields...)
+    case dtoo.FirstOf:
+      // Every candidate is expected to extract the same type.
+      if len(m) > 0 {
+        return typeOf(m[0], values)
+      }
+    case *dtoo.CompiledModel:
+      return typeOf(m.Model(), values)
+  }
+
+  return inferType(values)
+}
+
+// inferType returns the type of values. Values of conflicting types are stored as strings, as are
+// values of no type at all.
+func inferType(values []interface{}) arrow.DataType {
+  var inferred arrow.DataType
+  objects := make([]interface{}, 0)
+  lists := make([]interface{}, 0)
+
+  for _,value := range(values) {
+    var t arrow.DataType
+
+    switch value.(type) {
+      case nil:
+        continue
+      case dtoo.Model, dtoo.OrderedModel, map[string]interface{}:
+        objects = append(objects, value)
+        t = arrow.StructOf()
+      default:
+        switch v := reflect.ValueOf(value); v.Kind() {
+          case reflect.String:
+            t = arrow.BinaryTypes.String
+          case reflect.Bool:
+            t = arrow.FixedWidthTypes.Boolean
+          case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+            reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+            t = arrow.PrimitiveTypes.Int64
+          case reflect.Float32, reflect.Float64:
+            t = arrow.PrimitiveTypes.Float64
+          case reflect.Slice, reflect.Array:
+            lists = append(lists, value)
+            t = arrow.ListOf(arrow.Null)
+          default:
+            t = arrow.BinaryTypes.String
+        }
+    }
+
+    switch {
+      case inferred == nil:
+        inferred = t
+      case inferred.ID() == t.ID():
+      case isNumber(inferred) && isNumber(t):
+        inferred = arrow.PrimitiveTypes.Float64
+      default:
+        return arrow.BinaryTypes.String
+    }
+  }
+
+  if inferred == nil {
+    return arrow.BinaryTypes.String
+  }
+
+  switch inferred.ID() {
+    case arrow.STRUCT:
+      return inferStruct(objects)
+    case arrow.LIST:
+      return arrow.ListOf(inferType(elements(lists)))
+  }
+
+  return inferred
+}
+
+// inferStruct returns the struct type of objects, with a field for every key of any of them. Model keys
+// are ordered by name and OrderedModel keys keep the order they are first seen in.
+func inferStruct(objects []interface{}) arrow.DataType {
+  keys := make([]string, 0)
+  seen := map[string]bool{}
+  add := func (key string) {
+    if !seen[key] {
+      seen[key] = true
+      keys = append(keys, key)
+    }
+  }
+
+  for _,object := range(objects) {
+    switch o := object.(type) {
+      case dtoo.OrderedModel:
+        for _,key := range(o.Keys()) {
+          add(key)
+        }
+      default:
+        for _,key := range(sortedKeys(object)) {
+          add(key)
+        }
+    }
+  }
+
+  fields := make([]arrow.Field, len(keys))
+
+  for i,key := range(keys) {
+    fields[i] = arrow.Field{Name: key, Type: inferType(fieldValues(objects, key)), Nullable: true}
+  }
+
+  return arrow.StructOf(fields...)
+}
+
+func isNumber(t arrow.DataType) bool {
+  return t.ID() == arrow.INT64 || t.ID() == arrow.FLOAT64
+}
+
+// fieldValues returns the value of key of each of values, or nil for values that are not objects.
+func fieldValues(values []interface{}, key string) []interface{} {
+  fields := make([]interface{}, len(values))
+
+  for i,value := range(values) {
+    fields[i] = field(value, key)
+  }
+
+  return fields
+}
+
+// field returns the value of key of an object, or nil if object is not an object.
+func field(object interface{}, key string) interface{} {
+  switch o := object.(type) {
+    case dtoo.Model:
+      return o[key]
+    case map[string]interface{}:
+      return o[key]
+    case dtoo.OrderedModel:
+      value, _ := o.Get(key)
+      return value
+  }
+
+  return nil
+}
+
+func sortedKeys(object interface{}) []string {
+  var m map[string]interface{}
+
+  switch o := object.(type) {
+    case dtoo.Model:
+      m = o
+    case map[string]interface{}:
+      m = o
+  }
+
+  keys := make([]string, 0, len(m))
+
+  for key := range(m) {
+    keys = append(keys, key)
+  }
+
+  sort.Strings(keys)
+  return keys
+}
+
+// elements returns the elements of every slice among values.
+func elements(values []interface{}) []interface{} {
+  all := make([]interface{}, 0)
+
+  for _,value := range(values) {
+    if v := reflect.ValueOf(value); value != nil && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+      if _,isModel := value.(dtoo.OrderedModel); !isModel {
+        for i := 0; i < v.Len(); i++ {
+          all = append(all, v.Index(i).Interface())
+        }
+      }
+    }
+  }
+
+  return all
+}
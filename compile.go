@@ -0,0 +1,155 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "sync"
+  "reflect"
+  "github.com/andybalholm/cascadia"
+  "github.com/PuerkitoBio/goquery"
+)
+
+/*
+CompiledModel is a data model that has been validated and had all of its CSS selectors compiled ahead
+of time by Compile. Scraping with a CompiledModel skips re-parsing selectors at every iteration, which
+makes repeated scrapes with the same model faster. A CompiledModel is safe for concurrent use and can be
+passed anywhere a data model is accepted.
+
+Example:
+
+    posts, err := dtoo.Compile(dtoo.Model{
+      "Id": "id",
+      "Title": dtoo.RetrieverModel{Sel: ".post-title", Method: "text"},
+    })
+
+    if err == nil {
+      for _,url := range(urls) {
+        dtoo.ScrapeFromUrl(".post", posts, url)
+      }
+    }
+*/
+type CompiledModel struct {
+  model interface{}
+  // The compiled selectors of the model, keyed by selector. Read-only after Compile.
+  selectors map[string]goquery.Matcher
+  // Compiled root iterators, keyed by selector. Holds goquery.Matcher values.
+  iterators sync.Map
+  // The plans of every Model of the model, keyed by the pointer of the Model. Read-only after Compile.
+  plans map[uintptr]modelPlan
+}
+
+// Compile validates the data model specified with ValidateModel and compiles all of its CSS selectors.
+// Returns the ValidationError if the model is invalid.
+//
+// Example:
+//
+//    compiled, err := dtoo.Compile(dtoo.Model{"Id": "id", "Title": dtoo.RetrieverModel{Sel: ".post-title", Method: "text"}})
+func Compile(model interface{}) (*CompiledModel, error) {
+  if err := ValidateModel(model); err != nil {
+    return nil, err
+  }
+
+  cm := &CompiledModel{model: model, selectors: map[string]goquery.Matcher{}, plans: map[uintptr]modelPlan{}}
+
+  walkModel(model, EMPTYSTRING, func (path string, model interface{}) {
+    // ValidateModel has already checked that Computed keys don't form a cycle.
+    if m,ok := model.(Model); ok {
+      if plan,err := newModelPlan(m); err == nil {
+        cm.plans[reflect.ValueOf(m).Pointer()] = plan
+      }
+    }
+
+    for _,sel := range(modelSelectors(model)) {
+      if _,compiled := cm.selectors[sel]; sel != EMPTYSTRING && !compiled {
+        // ValidateModel has already checked that every selector compiles.
+        cm.selectors[sel] = cascadia.MustCompile(sel)
+      }
+    }
+  })
+
+  return cm, nil
+}
+
+// Scrape scrapes content from a goquery.Selection object according to the compiled data model and options specified.
+// Takes a selector as its root iterator. The iterator is compiled on first use and reused by later calls.
+//
+// Example:
+//
+//    compiled.Scrape(".post", doc.Selection, dtoo.ScrapeOptions{})
+func (cm *CompiledModel) Scrape(iterator string, s *goquery.Selection, opts ScrapeOptions) ([]interface{}, error) {
+  return ScrapeWithOptions(iterator, cm, s, opts)
+}
+
+// Model returns the data model that was compiled.
+func (cm *CompiledModel) Model() interface{} {
+  return cm.model
+}
+
+// matcher returns the compiled form of sel or nil if sel is not a valid selector.
+func (cm *CompiledModel) matcher(sel string) goquery.Matcher {
+  if m,ok := cm.selectors[sel]; ok {
+    return m
+  }
+
+  if m,ok := cm.iterators.Load(sel); ok {
+    return m.(goquery.Matcher)
+  }
+
+  if m,err := cascadia.Compile(sel); err == nil {
+    cm.iterators.Store(sel, goquery.Matcher(m))
+    return m
+  }
+
+  return nil
+}
+
+// matcher returns the compiled form of sel if a compiled model is being extracted, otherwise nil.
+func (sc *scraper) matcher(sel string) goquery.Matcher {
+  if sc.compiled != nil {
+    return sc.compiled.matcher(sel)
+  }
+
+  return nil
+}
+
+// find returns the descendants of s that match sel, using the compiled selector if there is one.
+func (sc *scraper) find(s *goquery.Selection, sel string) *goquery.Selection {
+  if m := sc.matcher(sel); m != nil {
+    return s.FindMatcher(m)
+  }
+
+  return s.Find(sel)
+}
+
+func isMethodName(method string) bool {
+  return method == "text" || method == "html" || method == "absUrl"
+}
+
+func displayPath(path string) string {
+  if path == EMPTYSTRING {
+    return "model"
+  }
+
+  return path
+}
+
+// modelSelectors returns the CSS selectors used directly by model, not including those of nested models.
+// Unset selectors are returned as empty strings.
+func modelSelectors(model interface{}) []string {
+  switch modelValue := model.(type) {
+    case RetrieverModel:
+      return []string{modelValue.Sel, modelValue.Scrape.Iterator, modelValue.Scrape.Options.Not}
+    case Switch:
+      selectors := make([]string, 0, 2 * len(modelValue.Cases))
+
+      for _,c := range(modelValue.Cases) {
+        selectors = append(selectors, c.Is, c.Has)
+      }
+
+      return selectors
+  }
+
+  return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "fmt"
+  "strings"
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+const steamFixture = "./fixtures/replay/GET_store.steampowered.com_search_cc83eeba.body"
+
+var steamModel = Model{
+  "Name": RetrieverModel{Sel: ".search_name h4", Method: "text"},
+  "DetailsUrl": "href",
+  "LogoSmall": RetrieverModel{Sel: ".search_capsule img", Attr: "src"},
+  "Metascore": RetrieverModel{Sel: ".search_metascore", Method: "text"},
+  "ReleaseDate": RetrieverModel{Sel: ".search_released", Method: "text"},
+  "Genres": RetrieverModel{Sel: ".search_name p", Method: "text"},
+}
+
+func loadSteamFixture(tb testing.TB) *goquery.Document {
+  file, err := os.Open(steamFixture)
+
+  if err != nil {
+    tb.Fatal(err)
+  }
+
+  defer file.Close()
+
+  doc, err := newDocument(file, EMPTYSTRING, EMPTYSTRING)
+
+  if err != nil {
+    tb.Fatal(err)
+  }
+
+  return doc
+}
+
+func TestCompiledModelMatchesModel(t *testing.T) {
+  doc := loadSteamFixture(t)
+  compiled, err := Compile(steamModel)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  expected, err := Scrape(".search_result_row", steamModel, doc.Selection, 0)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  actual, err := compiled.Scrape(".search_result_row", doc.Selection, ScrapeOptions{Workers: 4})
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if fmt.Sprint(expected) != fmt.Sprint(actual) {
+    t.Fatalf("compiled results differ: expected %v got %v", expected, actual)
+  }
+
+  // A compiled model is also accepted anywhere a data model is.
+  if nested,err := Scrape("#search_result_container", RetrieverModel{Scrape: ScrapeObject{Iterator: ".search_result_row", Data: compiled}}, doc.Selection, 0); err == nil {
+    if fmt.Sprint(nested[0]) != fmt.Sprint(expected) {
+      t.Fatalf("nested compiled results differ: expected %v got %v", expected, nested[0])
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestCompileRejectsInvalidModels(t *testing.T) {
+  tests := []struct {
+    model interface{}
+    err string
+  }{
+    {Model{"Title": RetrieverModel{Sel: "h1[", Method: "text"}}, `Title: invalid selector "h1["`},
+    {Model{"Title": RetrieverModel{Sel: "h1", Method: "txt"}}, `Title: unrecognized method "txt"`},
+    {Model{"Count": 3}, `Count: unsupported retriever type int`},
+    {OrderedModel{{Key: "Comments", Value: RetrieverModel{Scrape: ScrapeObject{Iterator: ".comment", Data: Model{"Author": RetrieverModel{}}}}}}, `Comments.Scrape.Data.Author: empty RetrieverModel`},
+  }
+
+  for _,test := range(tests) {
+    if _,err := Compile(test.model); err == nil || !strings.HasPrefix(err.Error(), test.err) {
+      t.Fatalf("invalid error: expected %v got %v", test.err, err)
+    }
+  }
+}
+
+func BenchmarkScrapeModel(b *testing.B) {
+  doc := loadSteamFixture(b)
+  b.ResetTimer()
+
+  for i := 0; i < b.N; i++ {
+    if _,err := Scrape(".search_result_row", steamModel, doc.Selection, 0); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
+
+func BenchmarkScrapeCompiledModel(b *testing.B) {
+  doc := loadSteamFixture(b)
+  compiled, err := Compile(steamModel)
+
+  if err != nil {
+    b.Fatal(err)
+  }
+
+  b.ResetTimer()
+
+  for i := 0; i < b.N; i++ {
+    if _,err := compiled.Scrape(".search_result_row", doc.Selection, ScrapeOptions{}); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
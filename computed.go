@@ -0,0 +1,119 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "errors"
+  "strings"
+)
+
+/*
+Computed is a Model or OrderedModel value that is computed from the values already extracted for other
+keys of the same model instead of being scraped. Computed keys are evaluated after every other key, each
+one after the keys listed in its Deps, which can themselves be computed.
+
+Example:
+
+    dtoo.Model{
+      "LogoSmall": dtoo.RetrieverModel{Sel: ".capsule img", Attr: "src"},
+      "Logo": dtoo.Computed{Deps: []string{"LogoSmall"}, Func: func (data dtoo.Model) (interface{}, error) {
+        return strings.Replace(data["LogoSmall"].(string), "sm_120", "184x69", 1), nil
+      }},
+    }
+*/
+type Computed struct {
+  // The keys of the enclosing model that Func reads.
+  Deps []string
+  // Computes the value from the values extracted so far, keyed the same as the enclosing model.
+  Func func (data Model) (interface{}, error)
+}
+
+// computedOrder returns the computed keys among keys ordered so that every key comes after its dependencies.
+// The relative order of keys is kept where dependencies allow. lookup returns the model value for a key.
+func computedOrder(keys []string, lookup func (key string) (interface{}, bool)) ([]string, error) {
+  const (
+    unvisited = iota
+    visiting
+    visited
+  )
+
+  state := map[string]int{}
+  order := make([]string, 0)
+  stack := make([]string, 0)
+
+  var visit func (key string) error
+  visit = func (key string) error {
+    switch state[key] {
+      case visited:
+        return nil
+      case visiting:
+        for i,k := range(stack) {
+          if k == key {
+            return errors.New("Computed keys form a cycle: " + strings.Join(append(stack[i:], key), " -> "))
+          }
+        }
+    }
+
+    model, _ := lookup(key)
+    computed, ok := model.(Computed)
+
+    if !ok {
+      state[key] = visited
+      return nil
+    }
+
+    state[key] = visiting
+    stack = append(stack, key)
+
+    for _,dep := range(computed.Deps) {
+      if _,exists := lookup(dep); !exists {
+        return errors.New("Computed key " + key + " depends on unknown key " + dep)
+      }
+
+      if err := visit(dep); err != nil {
+        return err
+      }
+    }
+
+    stack = stack[:len(stack) - 1]
+    state[key] = visited
+    order = append(order, key)
+    return nil
+  }
+
+  for _,key := range(keys) {
+    if err := visit(key); err != nil {
+      return nil, err
+    }
+  }
+
+  return order, nil
+}
+
+// compute evaluates the computed keys in order, storing each value in data as it is computed.
+func compute(order []string, lookup func (key string) (interface{}, bool), data Model) error {
+  for _,key := range(order) {
+    model, _ := lookup(key)
+    computed := model.(Computed)
+
+    if computed.Func == nil {
+      return errors.New("Computed key " + key + " has no Func")
+    }
+
+    if value,err := computed.Func(data); err == nil {
+      data[key] = value
+    } else {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// isComputed reports whether model is a Computed value.
+func isComputed(model interface{}) bool {
+  _,ok := model.(Computed)
+  return ok
+}
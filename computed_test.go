@@ -0,0 +1,83 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "fmt"
+  "strings"
+  "testing"
+)
+
+func TestComputed(t *testing.T) {
+  upper := Computed{Deps: []string{"Title"}, Func: func (data Model) (interface{}, error) {
+    return strings.ToUpper("item " + data["Title"].(string)), nil
+  }}
+  // Depends on another computed key that sorts after it.
+  banner := Computed{Deps: []string{"Upper", "Id"}, Func: func (data Model) (interface{}, error) {
+    return fmt.Sprintf("%v: %v", data["Id"], data["Upper"]), nil
+  }}
+
+  for _,model := range([]interface{}{
+    Model{"Id": "id", "Title": "text", "Upper": upper, "Banner": banner},
+    OrderedModel{{Key: "Banner", Value: banner}, {Key: "Upper", Value: upper}, {Key: "Id", Value: "id"}, {Key: "Title", Value: "text"}},
+  }) {
+    for _,opts := range([]ScrapeOptions{{}, {Workers: 4, ParallelKeys: true}}) {
+      items, err := ScrapeFromStringWithOptions("li", model, numberedList(3), opts)
+
+      if err != nil {
+        t.Fatal(err)
+      }
+
+      for i,item := range(items) {
+        data, ok := item.(Model)
+
+        if !ok {
+          data = item.(OrderedModel).Model()
+        }
+
+        expected := fmt.Sprintf("%d: ITEM %d", i, i)
+
+        if data["Banner"] != expected {
+          t.Fatalf("invalid computed value: expected %v got %v", expected, data["Banner"])
+        }
+      }
+    }
+  }
+}
+
+func TestComputedErrors(t *testing.T) {
+  constant := func (value interface{}) func (data Model) (interface{}, error) {
+    return func (data Model) (interface{}, error) { return value, nil }
+  }
+
+  tests := []struct {
+    model interface{}
+    expected string
+  }{
+    {Model{"A": Computed{Deps: []string{"B"}, Func: constant(1)}, "B": Computed{Deps: []string{"A"}, Func: constant(2)}}, "Computed keys form a cycle: A -> B -> A"},
+    {OrderedModel{{Key: "A", Value: Computed{Deps: []string{"A"}, Func: constant(1)}}}, "Computed keys form a cycle: A -> A"},
+    {Model{"A": Computed{Deps: []string{"Missing"}, Func: constant(1)}}, "Computed key A depends on unknown key Missing"},
+    {Model{"A": Computed{}}, "Computed key A has no Func"},
+    {Computed{Func: constant(1)}, "Computed can only be used as a Model or OrderedModel value"},
+  }
+
+  for _,test := range(tests) {
+    if _,err := ScrapeFromString("li", test.model, numberedList(1)); err == nil || err.Error() != test.expected {
+      t.Fatalf("invalid error: expected %v got %v", test.expected, err)
+    }
+  }
+
+  if err := ValidateModel(tests[0].model); err == nil || err.Error() != "model: Computed keys form a cycle: A -> B -> A" {
+    t.Fatalf("invalid validation error: %v", err)
+  }
+
+  if err := ValidateModel(tests[4].model); err == nil || err.Error() != "model: Computed can only be used as a Model or OrderedModel value" {
+    t.Fatalf("invalid validation error: %v", err)
+  }
+
+  if err := ValidateModel(Model{"A": FirstOf{Computed{Func: constant(1)}, "id"}}); err == nil || err.Error() != "A.FirstOf[0]: Computed can only be used as a Model or OrderedModel value" {
+    t.Fatalf("invalid validation error: %v", err)
+  }
+}
@@ -0,0 +1,113 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "context"
+  "net/url"
+  "strconv"
+  "github.com/PuerkitoBio/goquery"
+)
+
+/*
+ScrapeContext describes where in a scrape a func retriever is being called. It is passed to func retrievers
+with the extended signature func(*goquery.Selection, *dtoo.ScrapeContext)(interface{}, error), which can be
+used anywhere the func(*goquery.Selection)(interface{}, error) signature can.
+
+Example:
+
+    dtoo.Model{
+      "Title": dtoo.RetrieverModel{Sel: ".post-title", Method: "text"},
+      "Slug": func (s *goquery.Selection, ctx *dtoo.ScrapeContext) (interface{}, error) {
+        title, _ := ctx.Parent["Title"].(string)
+        return fmt.Sprintf("%d-%s", ctx.Index, strings.ToLower(title)), nil
+      },
+    }
+*/
+type ScrapeContext struct {
+  // The index of the item being extracted in the innermost iteration, counting skipped items. -1 outside of an iteration.
+  Index int
+  // Where the value being extracted is in the results, i.e. "[3].Comments[0].Author".
+  Path string
+  // The URL the document was fetched from. Can be nil.
+  Url *url.URL
+  // The innermost Model being built, holding the keys already extracted. Keys are extracted in the order
+  // described by Model and OrderedModel. When the ParallelKeys option is set no sibling keys are available.
+  // Must not be modified.
+  Parent Model
+  // The user-supplied values from ScrapeOptions.Values.
+  Values map[string]interface{}
+  // Done once the scrape is cancelled, i.e. by the context passed to ScrapeFromUrlWithContext. Long
+  // running func retrievers should return its error once it is done.
+  Context context.Context
+  doc *scrapedDocument
+}
+
+// Root returns the root of the document being scraped, or nil if there is none.
+func (ctx *ScrapeContext) Root() *goquery.Selection {
+  if ctx.doc == nil {
+    return nil
+  }
+
+  return ctx.doc.root()
+}
+
+// BaseUrl returns the URL relative URLs in the document are resolved against, or nil if there is none.
+func (ctx *ScrapeContext) BaseUrl() *url.URL {
+  if ctx.doc == nil {
+    return nil
+  }
+
+  return ctx.doc.base()
+}
+
+// rootContext returns the context of a scrape of the document being scraped by sc.
+func (sc *scraper) rootContext() *ScrapeContext {
+  return &ScrapeContext{
+    Index: -1,
+    Url: sc.docUrl,
+    Values: sc.opts.Values,
+    Context: sc.ctx,
+    doc: sc.doc,
+  }
+}
+
+// item returns the context of the i-th item of an iteration extracted with model.
+func (ctx *ScrapeContext) item(i int, model interface{}) *ScrapeContext {
+  if !usesContext(model) {
+    return ctx
+  }
+
+  child := *ctx
+  child.Index = i
+  child.Path = ctx.Path + "[" + strconv.Itoa(i) + "]"
+  return &child
+}
+
+// field returns the context of the key of the Model being built as parent, extracted with model.
+func (ctx *ScrapeContext) field(key string, parent Model, model interface{}) *ScrapeContext {
+  if !usesContext(model) {
+    return ctx
+  }
+
+  child := *ctx
+  child.Path = joinPath(ctx.Path, key)
+  child.Parent = parent
+  return &child
+}
+
+// usesContext reports whether extracting model can read its ScrapeContext. Attributes, text and html are
+// retrieved without one, so no context is built for them.
+func usesContext(model interface{}) bool {
+  switch modelValue := model.(type) {
+    case string:
+      return false
+    case RetrieverModel:
+      _,isName := modelValue.Method.(string)
+      return modelValue.Scrape.Iterator != EMPTYSTRING || (modelValue.Method != nil && !isName)
+  }
+
+  return true
+}
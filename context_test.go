@@ -0,0 +1,93 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "fmt"
+  "context"
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+const contextHtml = `<html><head><base href="/blog/"></head><body>
+<div class="post"><h3>First</h3><p class="comment">a</p><p class="comment">b</p></div>
+<div class="post"><h3>Second</h3><p class="comment">c</p></div>
+</body></html>`
+
+func TestScrapeContext(t *testing.T) {
+  describe := func (s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
+    title, _ := ctx.Parent["Title"].(string)
+    return fmt.Sprintf("%d %s %s %s %v", ctx.Index, ctx.Path, title, ctx.BaseUrl(), ctx.Values["site"]), nil
+  }
+
+  model := OrderedModel{
+    {Key: "Title", Value: RetrieverModel{Sel: "h3", Method: "text"}},
+    {Key: "Description", Value: describe},
+    {Key: "Comments", Value: RetrieverModel{Scrape: ScrapeObject{Iterator: ".comment", Data: Model{
+      "Text": "text",
+      "Where": RetrieverModel{Method: describe},
+    }}}},
+  }
+
+  opts := ScrapeOptions{BaseUrl: "http://example.com/", Values: map[string]interface{}{"site": "example"}}
+  posts, err := ScrapeFromStringWithOptions(".post", model, contextHtml, opts)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  expected := []string{
+    "0 [0].Description First http://example.com/blog/ example",
+    "1 [1].Description Second http://example.com/blog/ example",
+  }
+
+  for i,post := range(posts) {
+    if description,_ := post.(OrderedModel).Get("Description"); description != expected[i] {
+      t.Fatalf("invalid description: expected %v got %v", expected[i], description)
+    }
+  }
+
+  comments, _ := posts[0].(OrderedModel).Get("Comments")
+  where := comments.([]interface{})[1].(Model)["Where"]
+
+  if where != "1 [0].Comments[1].Where  http://example.com/blog/ example" {
+    t.Fatalf("invalid nested context: expected %v got %v", "1 [0].Comments[1].Where  http://example.com/blog/ example", where)
+  }
+}
+
+func TestScrapeContextRoot(t *testing.T) {
+  root := func (s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
+    if ctx.Root() == nil {
+      return nil, fmt.Errorf("no document root")
+    }
+
+    return ctx.Root().Find(".post").Length(), nil
+  }
+
+  if counts,err := ScrapeFromString(".comment", root, contextHtml); err == nil {
+    if fmt.Sprint(counts) != "[2 2 2]" {
+      t.Fatalf("invalid counts: expected %v got %v", "[2 2 2]", counts)
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestScrapeContextCancel(t *testing.T) {
+  ctx, cancel := context.WithCancel(context.Background())
+  cancelFirst := func (s *goquery.Selection, sc *ScrapeContext) (interface{}, error) {
+    if sc.Context != ctx {
+      return nil, fmt.Errorf("invalid context")
+    }
+
+    cancel()
+    return sc.Index, nil
+  }
+
+  // Extraction stops at the first item after the context is done.
+  if indexes,err := ScrapeFromStringWithContext(ctx, ".comment", cancelFirst, contextHtml, ScrapeOptions{}); err != context.Canceled || fmt.Sprint(indexes) != "[0]" {
+    t.Fatalf("invalid indexes: %v (%v)", indexes, err)
+  }
+}
@@ -0,0 +1,362 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "fmt"
+  "sort"
+  "encoding/json"
+)
+
+// retrieverKeys are the keys of a JSON object that decodes to a RetrieverModel.
+var retrieverKeys = map[string]bool{
+  "sel": true,
+  "attr": true,
+  "method": true,
+  "scrape": true,
+  "defaultValue": true,
+  "defaultOnError": true,
+}
+
+/*
+DecodeModel decodes a data model serialized as JSON, so models can be stored in files or sent to a
+server. Func retrievers and Computed keys can't be serialized. The JSON forms of the data model types are:
+
+    "text"                                                  a string data model
+    {"Title": ..., "Date": ...}                             a Model
+    {"sel": ".post-title", "attr": "href",                  a RetrieverModel
+     "method": "text", "defaultValue": "none",
+     "defaultOnError": true,
+     "scrape": {"iterator": ".comment", "data": ...,
+       "offset": 0, "limit": 10, "step": 1,
+       "reverse": false, "not": ".spam"}}
+    {"firstOf": [..., ...]}                                 a FirstOf
+    {"switch": [{"is": ".ad", "has": ".video",              a Switch
+      "attr": "data-type", "value": "video", "data": ...}],
+     "default": ...}
+    {"skip": true}                                          Skip
+    {"$model": {...}}                                       a Model whose keys would otherwise be mistaken
+                                                            for one of the forms above
+
+An object is a RetrieverModel if it has a "sel", "attr", "method" or "scrape" key and every key is one of
+the RetrieverModel keys. The decoded model is validated with ValidateModel.
+
+Example:
+
+    model, err := dtoo.DecodeModel([]byte(`{"Id": "id", "Title": {"sel": ".post-title", "method": "text"}}`))
+*/
+func DecodeModel(data []byte) (interface{}, error) {
+  var raw interface{}
+
+  if err := json.Unmarshal(data, &raw); err != nil {
+    return nil, err
+  }
+
+  model, err := decodeModel(raw, EMPTYSTRING)
+
+  if err != nil {
+    return nil, err
+  }
+
+  if err := ValidateModel(model); err != nil {
+    return nil, err
+  }
+
+  return model, nil
+}
+
+// JSONOptions are the ScrapeOptions that can be serialized as JSON, i.e. the options of a scrape request
+// to the server or of a job.
+//
+//     {"offset": 0, "limit": 10, "step": 1, "reverse": false, "not": ".spam", "charset": "utf-8",
+//      "baseUrl": "https://example.com/", "resolveUrls": true}
+type JSONOptions struct {
+  Offset uint `json:"offset"`
+  Limit uint `json:"limit"`
+  Step uint `json:"step"`
+  Reverse bool `json:"reverse"`
+  Not string `json:"not"`
+  Charset string `json:"charset"`
+  BaseUrl string `json:"baseUrl"`
+  ResolveUrls bool `json:"resolveUrls"`
+}
+
+// ScrapeOptions returns o as ScrapeOptions.
+func (o JSONOptions) ScrapeOptions() ScrapeOptions {
+  return ScrapeOptions{
+    Offset: o.Offset,
+    Limit: o.Limit,
+    Step: o.Step,
+    Reverse: o.Reverse,
+    Not: o.Not,
+    Charset: o.Charset,
+    BaseUrl: o.BaseUrl,
+    ResolveUrls: o.ResolveUrls,
+  }
+}
+
+func decodeModel(raw interface{}, path string) (interface{}, error) {
+  switch value := raw.(type) {
+    case string:
+      return value, nil
+    case map[string]interface{}:
+      return decodeObject(value, path)
+  }
+
+  return nil, ModelError{Path: path, Message: fmt.Sprintf("cannot decode %v as a data model", jsonType(raw))}
+}
+
+func decodeObject(object map[string]interface{}, path string) (interface{}, error) {
+  if len(object) == 1 {
+    if inner,ok := object["$model"].(map[string]interface{}); ok {
+      return decodeMap(inner, path)
+    }
+    if candidates,ok := object["firstOf"].([]interface{}); ok {
+      return decodeFirstOf(candidates, path)
+    }
+    if skip,ok := object["skip"].(bool); ok && skip {
+      return Skip, nil
+    }
+  }
+
+  if cases,ok := object["switch"].([]interface{}); ok && (len(object) == 1 || (len(object) == 2 && object["default"] != nil)) {
+    return decodeSwitch(cases, object["default"], path)
+  }
+
+  if isRetrieverObject(object) {
+    return decodeRetrieverModel(object, path)
+  }
+
+  return decodeMap(object, path)
+}
+
+func isRetrieverObject(object map[string]interface{}) bool {
+  found := false
+
+  for key := range(object) {
+    if !retrieverKeys[key] {
+      return false
+    }
+
+    found = found || key == "sel" || key == "attr" || key == "method" || key == "scrape"
+  }
+
+  return found
+}
+
+func decodeMap(object map[string]interface{}, path string) (Model, error) {
+  model := Model{}
+
+  for _,key := range(objectKeys(object)) {
+    if value,err := decodeModel(object[key], joinPath(path, key)); err == nil {
+      model[key] = value
+    } else {
+      return nil, err
+    }
+  }
+
+  return model, nil
+}
+
+func decodeFirstOf(candidates []interface{}, path string) (FirstOf, error) {
+  firstOf := make(FirstOf, len(candidates))
+
+  for i,candidate := range(candidates) {
+    if value,err := decodeModel(candidate, joinPath(path, fmt.Sprintf("FirstOf[%d]", i))); err == nil {
+      firstOf[i] = value
+    } else {
+      return nil, err
+    }
+  }
+
+  return firstOf, nil
+}
+
+func decodeSwitch(cases []interface{}, def interface{}, path string) (Switch, error) {
+  sw := Switch{Cases: make([]Case, len(cases))}
+
+  for i,raw := range(cases) {
+    casePath := joinPath(path, fmt.Sprintf("Cases[%d]", i))
+    object, ok := raw.(map[string]interface{})
+
+    if !ok {
+      return sw, ModelError{Path: casePath, Message: fmt.Sprintf("cannot decode %v as a Case", jsonType(raw))}
+    }
+
+    c := &sw.Cases[i]
+    fields := map[string]*string{"is": &c.Is, "has": &c.Has, "attr": &c.Attr, "value": &c.Value}
+
+    for _,key := range(objectKeys(object)) {
+      value := object[key]
+
+      if key == "data" {
+        continue
+      }
+
+      if field,known := fields[key]; !known {
+        return sw, ModelError{Path: casePath, Message: fmt.Sprintf("unknown Case key %q", key)}
+      } else if *field,ok = value.(string); !ok {
+        return sw, ModelError{Path: casePath, Message: fmt.Sprintf("%v must be a string", key)}
+      }
+    }
+
+    if object["data"] != nil {
+      data, err := decodeModel(object["data"], joinPath(casePath, "Data"))
+
+      if err != nil {
+        return sw, err
+      }
+
+      c.Data = data
+    }
+  }
+
+  if def != nil {
+    data, err := decodeModel(def, joinPath(path, "Default"))
+
+    if err != nil {
+      return sw, err
+    }
+
+    sw.Default = data
+  }
+
+  return sw, nil
+}
+
+func decodeRetrieverModel(object map[string]interface{}, path string) (RetrieverModel, error) {
+  rm := RetrieverModel{DefaultValue: object["defaultValue"]}
+  invalid := func (key string, kind string) error {
+    return ModelError{Path: path, Message: fmt.Sprintf("%v must be a %v", key, kind)}
+  }
+
+  for _,key := range([]string{"sel", "attr", "method"}) {
+    if value,exists := object[key]; exists {
+      text, ok := value.(string)
+
+      if !ok {
+        return rm, invalid(key, "string")
+      }
+
+      switch key {
+        case "sel":
+          rm.Sel = text
+        case "attr":
+          rm.Attr = text
+        case "method":
+          rm.Method = text
+      }
+    }
+  }
+
+  if value,exists := object["defaultOnError"]; exists {
+    var ok bool
+
+    if rm.DefaultOnError,ok = value.(bool); !ok {
+      return rm, invalid("defaultOnError", "bool")
+    }
+  }
+
+  if value,exists := object["scrape"]; exists {
+    scrape, ok := value.(map[string]interface{})
+
+    if !ok {
+      return rm, invalid("scrape", "object")
+    }
+
+    var err error
+
+    if rm.Scrape,err = decodeScrapeObject(scrape, path); err != nil {
+      return rm, err
+    }
+  }
+
+  return rm, nil
+}
+
+// Returns the keys of a decoded JSON object in sorted order, so the first error reported is deterministic.
+func objectKeys(object map[string]interface{}) []string {
+  keys := make([]string, 0, len(object))
+
+  for key := range(object) {
+    keys = append(keys, key)
+  }
+
+  sort.Strings(keys)
+
+  return keys
+}
+
+func decodeScrapeObject(object map[string]interface{}, path string) (ScrapeObject, error) {
+  so := ScrapeObject{}
+  invalid := func (key string, kind string) error {
+    return ModelError{Path: joinPath(path, "Scrape"), Message: fmt.Sprintf("%v must be a %v", key, kind)}
+  }
+
+  for _,key := range(objectKeys(object)) {
+    value := object[key]
+    var ok bool
+
+    switch key {
+      case "iterator":
+        if so.Iterator,ok = value.(string); !ok {
+          return so, invalid(key, "string")
+        }
+      case "not":
+        if so.Options.Not,ok = value.(string); !ok {
+          return so, invalid(key, "string")
+        }
+      case "reverse":
+        if so.Options.Reverse,ok = value.(bool); !ok {
+          return so, invalid(key, "bool")
+        }
+      case "offset", "limit", "step":
+        n, isNumber := value.(float64)
+
+        if !isNumber || n < 0 || n != float64(uint(n)) {
+          return so, invalid(key, "non-negative integer")
+        }
+
+        switch key {
+          case "offset":
+            so.Options.Offset = uint(n)
+          case "limit":
+            so.Options.Limit = uint(n)
+          case "step":
+            so.Options.Step = uint(n)
+        }
+      case "data":
+        data, err := decodeModel(value, joinPath(path, "Scrape.Data"))
+
+        if err != nil {
+          return so, err
+        }
+
+        so.Data = data
+      default:
+        return so, ModelError{Path: joinPath(path, "Scrape"), Message: fmt.Sprintf("unknown scrape key %q", key)}
+    }
+  }
+
+  return so, nil
+}
+
+// jsonType describes the JSON type of a decoded JSON value.
+func jsonType(value interface{}) string {
+  switch value.(type) {
+    case nil:
+      return "null"
+    case bool:
+      return "a bool"
+    case float64:
+      return "a number"
+    case []interface{}:
+      return "an array"
+    case map[string]interface{}:
+      return "an object"
+  }
+
+  return "a string"
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "fmt"
+  "testing"
+  "encoding/json"
+)
+
+func TestDecodeModel(t *testing.T) {
+  model, err := DecodeModel([]byte(`{
+    "Title": {"sel": ".post-title", "method": "text"},
+    "Date": {"firstOf": [{"sel": "time", "attr": "datetime"}, {"sel": "time", "method": "text"}]},
+    "Kind": {"switch": [{"has": ".post-summary", "data": {"skip": true}}], "default": "id"},
+    "Options": {"$model": {"sel": {"sel": "h3", "method": "html", "defaultValue": "none", "defaultOnError": true}}},
+    "Links": {"scrape": {"iterator": "a", "data": "href", "limit": 2, "not": ".ad"}}
+  }`))
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  expected := Model{
+    "Title": RetrieverModel{Sel: ".post-title", Method: "text"},
+    "Date": FirstOf{RetrieverModel{Sel: "time", Attr: "datetime"}, RetrieverModel{Sel: "time", Method: "text"}},
+    "Kind": Switch{Cases: []Case{{Has: ".post-summary", Data: Skip}}, Default: "id"},
+    "Options": Model{"sel": RetrieverModel{Sel: "h3", Method: "html", DefaultValue: "none", DefaultOnError: true}},
+    "Links": RetrieverModel{Scrape: ScrapeObject{Iterator: "a", Data: "href", Options: ScrapeOptions{Limit: 2, Not: ".ad"}}},
+  }
+
+  if fmt.Sprintf("%#v", model) != fmt.Sprintf("%#v", expected) {
+    t.Fatalf("invalid model: expected\n%#v\ngot\n%#v", expected, model)
+  }
+
+  file, err := os.Open("./fixtures/index.html")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer file.Close()
+  model, _ = DecodeModel([]byte(`{"Title": {"sel": ".post-title", "method": "text"}, "Date": {"sel": "time", "method": "text"}}`))
+
+  if posts,err := ScrapeFromReaderWithLimit(".post", model, file, 1); err != nil || fmt.Sprint(posts) != "[map[Date:August 26th, 2014 Title:Some Post 1  ]]" {
+    t.Fatalf("invalid posts: %v (%v)", posts, err)
+  }
+}
+
+func TestDecodeModelErrors(t *testing.T) {
+  tests := []struct {
+    json string
+    expected string
+  }{
+    {`{"Title": 1}`, "Title: cannot decode a number as a data model"},
+    {`{"Title": {"sel": 1}}`, "Title: sel must be a string"},
+    {`{"Links": {"scrape": {"iterator": "a", "data": "href", "limit": -1}}}`, "Links.Scrape: limit must be a non-negative integer"},
+    {`{"Links": {"scrape": {"iterator": "a", "data": "href", "each": 1}}}`, `Links.Scrape: unknown scrape key "each"`},
+    {`{"Kind": {"switch": [{"is": ".ad", "when": "x"}]}}`, `Kind.Cases[0]: unknown Case key "when"`},
+    {`{"Kind": {"switch": [{"value": 1, "has": 2, "when": "x", "attr": 3}]}}`, `Kind.Cases[0]: attr must be a string`},
+    {`{"Links": {"scrape": {"iterator": 1, "data": "href", "limit": -1, "not": 2, "each": 1}}}`, `Links.Scrape: unknown scrape key "each"`},
+    {`{"Title": {"sel": ".post-title", "method": "txt"}}`, `Title: unrecognized method "txt"`},
+    {`{"Title": `, "unexpected end of JSON input"},
+  }
+
+  for _,test := range(tests) {
+    if _,err := DecodeModel([]byte(test.json)); err == nil || err.Error() != test.expected {
+      t.Fatalf("invalid error for %v: expected %v got %v", test.json, test.expected, err)
+    }
+  }
+}
+
+func TestJSONOptions(t *testing.T) {
+  var opts JSONOptions
+
+  if err := json.Unmarshal([]byte(`{"limit": 10, "not": ".spam", "baseUrl": "https://example.com/", "resolveUrls": true}`), &opts); err != nil {
+    t.Fatal(err)
+  }
+
+  if so := opts.ScrapeOptions(); so.Limit != 10 || so.Not != ".spam" || so.BaseUrl != "https://example.com/" || !so.ResolveUrls {
+    t.Fatalf("invalid options: %+v", so)
+  }
+}
@@ -0,0 +1,72 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "errors"
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+const defaultsHtml = `<div class="post">
+<a class="missing-href">Untitled</a>
+<img class="empty-alt" alt="">
+<span class="empty"></span>
+<a class="link" href="/posts/1">Post</a>
+</div>`
+
+func TestDefaultValue(t *testing.T) {
+  called := false
+  failing := errors.New("failed")
+
+  tests := []struct {
+    name string
+    rm RetrieverModel
+    expected interface{}
+  }{
+    {"missing attr", RetrieverModel{Sel: ".missing-href", Attr: "href", DefaultValue: "none"}, "none"},
+    {"empty attr", RetrieverModel{Sel: ".empty-alt", Attr: "alt", DefaultValue: "none"}, "none"},
+    {"present attr", RetrieverModel{Sel: ".link", Attr: "href", DefaultValue: "none"}, "/posts/1"},
+    {"empty text", RetrieverModel{Sel: ".empty", Method: "text", DefaultValue: "none"}, "none"},
+    {"empty html", RetrieverModel{Sel: ".empty", Method: "html", DefaultValue: "none"}, "none"},
+    {"zero-match text", RetrieverModel{Sel: ".nothing", Method: "text", DefaultValue: "none"}, "none"},
+    {"missing absUrl", RetrieverModel{Sel: ".missing-href", Method: "absUrl", DefaultValue: "none"}, "none"},
+    {"zero-match func", RetrieverModel{Sel: ".nothing", DefaultValue: "none", Method: func (s *goquery.Selection) (interface{}, error) {
+      called = true
+      return "called", nil
+    }}, "none"},
+    {"nil func", RetrieverModel{Sel: ".link", DefaultValue: "none", Method: func (s *goquery.Selection) (interface{}, error) {
+      return nil, nil
+    }}, "none"},
+    {"empty slice func", RetrieverModel{Sel: ".link", DefaultValue: "none", Method: func (s *goquery.Selection) (interface{}, error) {
+      return []string{}, nil
+    }}, "none"},
+    {"failing func with DefaultOnError", RetrieverModel{Sel: ".link", DefaultValue: "none", DefaultOnError: true, Method: func (s *goquery.Selection) (interface{}, error) {
+      return nil, failing
+    }}, "none"},
+    {"zero-match scrape", RetrieverModel{Scrape: ScrapeObject{Iterator: ".comment", Data: "text"}, DefaultValue: "none"}, "none"},
+    {"no default", RetrieverModel{Sel: ".empty", Method: "text"}, ""},
+  }
+
+  for _,test := range(tests) {
+    if items,err := ScrapeFromString(".post", test.rm, defaultsHtml); err == nil {
+      if items[0] != test.expected {
+        t.Fatalf("%v: invalid value: expected %v got %v", test.name, test.expected, items[0])
+      }
+    } else {
+      t.Fatalf("%v: %v", test.name, err)
+    }
+  }
+
+  if called {
+    t.Fatalf("method called for a selector that matches nothing")
+  }
+
+  if _,err := ScrapeFromString(".post", RetrieverModel{Sel: ".link", DefaultValue: "none", Method: func (s *goquery.Selection) (interface{}, error) {
+    return nil, failing
+  }}, defaultsHtml); err != failing {
+    t.Fatalf("invalid error: expected %v got %v", failing, err)
+  }
+}
@@ -0,0 +1,237 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package diff reports how the results of a dtoo data model changed between two runs.
+
+Items are matched across runs by the value of an identity key, i.e. "Id", and reported as added, removed
+or modified, with field-level changes for modified items. A Store keeps the results of the previous run
+in a local directory or a single file so Track can compare every run to the one before it.
+
+  store := &diff.DirStore{Dir: "snapshots"}
+  games, err := dtoo.ScrapeFromUrl(".search_result_row", model, url)
+
+  if report,err := diff.Track(store, "steam", games, "Id"); err == nil {
+    for _,m := range(report.Modified) {
+      log.Println(m)
+    }
+  }
+*/
+package diff
+
+import (
+  "fmt"
+  "sort"
+  "bytes"
+  "errors"
+  "reflect"
+  "strconv"
+  "strings"
+  "encoding/json"
+)
+
+// Entry is an added or removed item.
+type Entry struct {
+  Key string
+  Item interface{}
+}
+
+// Change is a field-level change of a modified item. Old is nil for added fields and New is nil for removed ones.
+type Change struct {
+  // Where the field is in the item, i.e. "Price" or "Genres[1]".
+  Path string
+  Old interface{}
+  New interface{}
+}
+
+func (c Change) String() string {
+  return fmt.Sprintf("%v: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Modification is an item present in both runs whose fields changed.
+type Modification struct {
+  Key string
+  Old interface{}
+  New interface{}
+  Changes []Change
+}
+
+func (m Modification) String() string {
+  changes := make([]string, len(m.Changes))
+
+  for i,c := range(m.Changes) {
+    changes[i] = c.String()
+  }
+
+  return m.Key + ": " + strings.Join(changes, ", ")
+}
+
+// Report lists the differences between two result sets. Added and Modified items are in the order of the
+// new results and Removed items in the order of the old results.
+type Report struct {
+  Added []Entry
+  Removed []Entry
+  Modified []Modification
+}
+
+// Empty reports whether the result sets were the same.
+func (r *Report) Empty() bool {
+  return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Modified) == 0
+}
+
+/*
+Compare compares two result sets whose items are Models or OrderedModels, matching items by the value of
+key. Results are compared in their JSON form so results loaded from a Store compare equal to freshly
+scraped ones, and the items and values in the Report are in that form too: objects are
+map[string]interface{} and numbers are json.Number.
+
+Returns an error if an item has no key or two items of the same result set have the same key.
+*/
+func Compare(old []interface{}, new []interface{}, key string) (*Report, error) {
+  oldItems, oldKeys, err := index(old, key)
+
+  if err != nil {
+    return nil, err
+  }
+
+  newItems, newKeys, err := index(new, key)
+
+  if err != nil {
+    return nil, err
+  }
+
+  report := &Report{Added: []Entry{}, Removed: []Entry{}, Modified: []Modification{}}
+
+  for _,k := range(newKeys) {
+    if oldItem,exists := oldItems[k]; !exists {
+      report.Added = append(report.Added, Entry{Key: k, Item: newItems[k]})
+    } else if changes := compareValues("", oldItem, newItems[k], []Change{}); len(changes) > 0 {
+      report.Modified = append(report.Modified, Modification{Key: k, Old: oldItem, New: newItems[k], Changes: changes})
+    }
+  }
+
+  for _,k := range(oldKeys) {
+    if _,exists := newItems[k]; !exists {
+      report.Removed = append(report.Removed, Entry{Key: k, Item: oldItems[k]})
+    }
+  }
+
+  return report, nil
+}
+
+// index normalizes results and maps each item by the value of its key. The keys are returned in order.
+func index(results []interface{}, key string) (map[string]interface{}, []string, error) {
+  normalized, err := normalize(results)
+
+  if err != nil {
+    return nil, nil, err
+  }
+
+  items, _ := normalized.([]interface{})
+  byKey := make(map[string]interface{}, len(items))
+  keys := make([]string, 0, len(items))
+
+  for i,item := range(items) {
+    object, ok := item.(map[string]interface{})
+
+    if !ok {
+      return nil, nil, errors.New("Item " + strconv.Itoa(i) + " is not a Model")
+    }
+
+    value, exists := object[key]
+
+    if !exists || value == nil {
+      return nil, nil, errors.New("Item " + strconv.Itoa(i) + " has no " + key)
+    }
+
+    k := fmt.Sprint(value)
+
+    if _,duplicate := byKey[k]; duplicate {
+      return nil, nil, errors.New("Duplicate " + key + " " + k)
+    }
+
+    byKey[k] = object
+    keys = append(keys, k)
+  }
+
+  return byKey, keys, nil
+}
+
+// normalize returns the JSON form of value.
+func normalize(value interface{}) (interface{}, error) {
+  data, err := json.Marshal(value)
+
+  if err != nil {
+    return nil, err
+  }
+
+  decoder := json.NewDecoder(bytes.NewReader(data))
+  decoder.UseNumber()
+
+  var normalized interface{}
+  err = decoder.Decode(&normalized)
+  return normalized, err
+}
+
+// compareValues appends the changes between the normalized values a and b at path to changes.
+func compareValues(path string, a interface{}, b interface{}, changes []Change) []Change {
+  switch aValue := a.(type) {
+    case map[string]interface{}:
+      if bValue,ok := b.(map[string]interface{}); ok {
+        for _,key := range(unionKeys(aValue, bValue)) {
+          changes = compareValues(joinPath(path, key), aValue[key], bValue[key], changes)
+        }
+
+        return changes
+      }
+    case []interface{}:
+      if bValue,ok := b.([]interface{}); ok {
+        for i := 0; i < len(aValue) || i < len(bValue); i++ {
+          var x, y interface{}
+
+          if i < len(aValue) {
+            x = aValue[i]
+          }
+          if i < len(bValue) {
+            y = bValue[i]
+          }
+
+          changes = compareValues(path + "[" + strconv.Itoa(i) + "]", x, y, changes)
+        }
+
+        return changes
+      }
+  }
+
+  if !reflect.DeepEqual(a, b) {
+    changes = append(changes, Change{Path: path, Old: a, New: b})
+  }
+
+  return changes
+}
+
+func unionKeys(a map[string]interface{}, b map[string]interface{}) []string {
+  keys := make([]string, 0, len(a))
+
+  for key := range(a) {
+    keys = append(keys, key)
+  }
+
+  for key := range(b) {
+    if _,exists := a[key]; !exists {
+      keys = append(keys, key)
+    }
+  }
+
+  sort.Strings(keys)
+  return keys
+}
+
+func joinPath(path string, key string) string {
+  if path == "" {
+    return key
+  }
+
+  return path + "." + key
+}
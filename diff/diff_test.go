@@ -0,0 +1,110 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+  "os"
+  "fmt"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+  "github.com/dschnare/dtoo"
+)
+
+func game(id string, price int, genres ...string) dtoo.Model {
+  return dtoo.Model{"Id": id, "Price": price, "Genres": genres}
+}
+
+func TestCompare(t *testing.T) {
+  old := []interface{}{game("1", 10, "Action"), game("2", 20, "RPG"), game("3", 30)}
+  new := []interface{}{
+    game("4", 40),
+    dtoo.OrderedModel{{Key: "Id", Value: "2"}, {Key: "Price", Value: 15}, {Key: "Genres", Value: []string{"RPG", "Indie"}}},
+    game("1", 10, "Action"),
+  }
+
+  report, err := Compare(old, new, "Id")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if len(report.Added) != 1 || report.Added[0].Key != "4" {
+    t.Fatalf("invalid added items: %v", report.Added)
+  }
+
+  if len(report.Removed) != 1 || report.Removed[0].Key != "3" {
+    t.Fatalf("invalid removed items: %v", report.Removed)
+  }
+
+  expected := "[2: Genres[1]: <nil> -> Indie, Price: 20 -> 15]"
+
+  if fmt.Sprint(report.Modified) != expected {
+    t.Fatalf("invalid modified items: expected %v got %v", expected, report.Modified)
+  }
+}
+
+func TestCompareErrors(t *testing.T) {
+  tests := []struct {
+    results []interface{}
+    expected string
+  }{
+    {[]interface{}{"1"}, "Item 0 is not a Model"},
+    {[]interface{}{game("1", 1), dtoo.Model{"Price": 1}}, "Item 1 has no Id"},
+    {[]interface{}{game("1", 1), game("1", 2)}, "Duplicate Id 1"},
+  }
+
+  for _,test := range(tests) {
+    if _,err := Compare([]interface{}{}, test.results, "Id"); err == nil || err.Error() != test.expected {
+      t.Fatalf("invalid error: expected %v got %v", test.expected, err)
+    }
+  }
+}
+
+func TestTrack(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-diff")
+  defer os.RemoveAll(dir)
+
+  stores := []Store{&DirStore{Dir: filepath.Join(dir, "snapshots")}, &FileStore{File: filepath.Join(dir, "snapshots.json")}}
+
+  for _,store := range(stores) {
+    if report,err := Track(store, "games", []interface{}{game("1", 10), game("2", 20)}, "Id"); err != nil || len(report.Added) != 2 {
+      t.Fatalf("invalid first report: %+v (%v)", report, err)
+    }
+
+    if report,err := Track(store, "games", []interface{}{game("1", 10), game("2", 20)}, "Id"); err != nil || !report.Empty() {
+      t.Fatalf("invalid unchanged report: %+v (%v)", report, err)
+    }
+
+    if report,err := Track(store, "games", []interface{}{game("1", 12)}, "Id"); err != nil || fmt.Sprint(report.Modified) != "[1: Price: 10 -> 12]" || len(report.Removed) != 1 {
+      t.Fatalf("invalid changed report: %+v (%v)", report, err)
+    }
+
+    if _,err := store.Load("other"); err != ErrNoSnapshot {
+      t.Fatalf("invalid error: expected %v got %v", ErrNoSnapshot, err)
+    }
+  }
+}
+
+func TestDirStoreNames(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-diff")
+  defer os.RemoveAll(dir)
+
+  store := &DirStore{Dir: filepath.Join(dir, "snapshots")}
+
+  for _,name := range([]string{"", "../games", "a/b", `a\b`, ".."}) {
+    if err := store.Save(name, nil); err == nil || err.Error() != fmt.Sprintf("Invalid snapshot name %q", name) {
+      t.Fatalf("invalid error for %q: %v", name, err)
+    }
+
+    if _,err := store.Load(name); err == nil || err == ErrNoSnapshot {
+      t.Fatalf("invalid error for %q: %v", name, err)
+    }
+  }
+
+  if files,_ := ioutil.ReadDir(dir); len(files) != 0 {
+    t.Fatalf("expected no files got %v", len(files))
+  }
+}
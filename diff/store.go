@@ -0,0 +1,172 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+  "os"
+  "fmt"
+  "sync"
+  "errors"
+  "strings"
+  "io/ioutil"
+  "encoding/json"
+  "path/filepath"
+  "github.com/dschnare/dtoo/internal/fsutil"
+)
+
+// ErrNoSnapshot is returned by a Store when no snapshot has been saved under a name.
+var ErrNoSnapshot = errors.New("No snapshot")
+
+// Store keeps the latest snapshot of result sets by name.
+type Store interface {
+  // Load returns the results saved under name, or ErrNoSnapshot.
+  Load(name string) ([]interface{}, error)
+  // Save replaces the results saved under name.
+  Save(name string, results []interface{}) error
+}
+
+// Track compares results to the snapshot saved in store under name and then saves results as the new
+// snapshot. If there is no snapshot yet then every item is reported as added.
+func Track(store Store, name string, results []interface{}, key string) (*Report, error) {
+  previous, err := store.Load(name)
+
+  if err == ErrNoSnapshot {
+    previous = []interface{}{}
+  } else if err != nil {
+    return nil, err
+  }
+
+  report, err := Compare(previous, results, key)
+
+  if err != nil {
+    return nil, err
+  }
+
+  return report, store.Save(name, results)
+}
+
+// DirStore is a Store that keeps each snapshot as a JSON file named after it in Dir. Names that are empty
+// or contain path separators or ".." are rejected so snapshots never escape Dir.
+type DirStore struct {
+  // The directory snapshots are stored in. Created if it does not exist.
+  Dir string
+}
+
+// Load implements Store.
+func (s *DirStore) Load(name string) ([]interface{}, error) {
+  results := []interface{}{}
+  file, err := s.file(name)
+
+  if err != nil {
+    return nil, err
+  }
+
+  if err = readJSON(file, &results); err != nil {
+    return nil, err
+  }
+
+  return results, nil
+}
+
+// Save implements Store.
+func (s *DirStore) Save(name string, results []interface{}) error {
+  file, err := s.file(name)
+
+  if err != nil {
+    return err
+  }
+
+  if err = os.MkdirAll(s.Dir, 0755); err != nil {
+    return err
+  }
+
+  return writeJSON(file, results)
+}
+
+// file returns the file the snapshot with the specified name is kept in.
+func (s *DirStore) file(name string) (string, error) {
+  if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+    return "", fmt.Errorf("Invalid snapshot name %q", name)
+  }
+
+  return filepath.Join(s.Dir, name + ".json"), nil
+}
+
+// FileStore is a Store that keeps every snapshot in a single JSON file, keyed by name.
+// It is safe for concurrent use within a process.
+type FileStore struct {
+  // The file snapshots are stored in. Created if it does not exist.
+  File string
+  mu sync.Mutex
+}
+
+// Load implements Store.
+func (s *FileStore) Load(name string) ([]interface{}, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  snapshots, err := s.read()
+
+  if err != nil {
+    return nil, err
+  }
+
+  if results,exists := snapshots[name]; exists {
+    return results, nil
+  }
+
+  return nil, ErrNoSnapshot
+}
+
+// Save implements Store.
+func (s *FileStore) Save(name string, results []interface{}) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  snapshots, err := s.read()
+
+  if err == ErrNoSnapshot {
+    snapshots = map[string][]interface{}{}
+  } else if err != nil {
+    return err
+  }
+
+  snapshots[name] = results
+  return writeJSON(s.File, snapshots)
+}
+
+func (s *FileStore) read() (map[string][]interface{}, error) {
+  snapshots := map[string][]interface{}{}
+
+  if err := readJSON(s.File, &snapshots); err != nil {
+    return nil, err
+  }
+
+  return snapshots, nil
+}
+
+// readJSON decodes file into v. Returns ErrNoSnapshot if file does not exist.
+func readJSON(file string, v interface{}) error {
+  data, err := ioutil.ReadFile(file)
+
+  if os.IsNotExist(err) {
+    return ErrNoSnapshot
+  } else if err != nil {
+    return err
+  }
+
+  return json.Unmarshal(data, v)
+}
+
+// writeJSON encodes v to file, replacing it atomically.
+func writeJSON(file string, v interface{}) error {
+  data, err := json.MarshalIndent(v, "", "  ")
+
+  if err != nil {
+    return err
+  }
+
+  return fsutil.WriteFileAtomic(file, data, 0644)
+}
@@ -0,0 +1,158 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "io"
+  "sort"
+  "strings"
+  "context"
+  "golang.org/x/net/html"
+  "github.com/PuerkitoBio/goquery"
+)
+
+// ScrapeFromDocument scrapes content from an already parsed goquery.Document according to the data model and options specified.
+// Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+// Relative URLs are resolved against doc.Url unless opts.BaseUrl is set.
+//
+// Returns a value based on the data model specified. See package examples for more info.
+//
+// Example:
+//
+//    dtoo.ScrapeFromDocument("li", dtoo.Model{id: 'id', content: 'text'}, doc, dtoo.ScrapeOptions{})
+func ScrapeFromDocument(iterator string, model interface{}, doc *goquery.Document, opts ScrapeOptions) ([]interface{}, error) {
+  return scrapeDocument(context.Background(), iterator, model, doc, opts)
+}
+
+// ScrapeFromNode scrapes content from a golang.org/x/net/html node according to the data model and options specified.
+// Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+//
+// Returns a value based on the data model specified. See package examples for more info.
+//
+// Example:
+//
+//    node, err := html.Parse(reader)
+//    if err == nil {
+//      dtoo.ScrapeFromNode("li", dtoo.Model{id: 'id', content: 'text'}, node, dtoo.ScrapeOptions{})
+//    }
+func ScrapeFromNode(iterator string, model interface{}, n *html.Node, opts ScrapeOptions) ([]interface{}, error) {
+  return scrapeDocument(context.Background(), iterator, model, goquery.NewDocumentFromNode(n), opts)
+}
+
+/*
+ScrapeAll scrapes several named result sets from a goquery.Selection object in a single call. Each
+ScrapeObject in sets specifies the root iterator, data model and iteration settings of one result set.
+Sets without iteration settings of their own use those of opts. The document is only examined once for
+settings shared by every set, such as its base URL.
+
+Returns a map of the result sets keyed by the same names as sets. If an error occurs then the result
+sets scraped so far are returned along with an error naming the set that failed.
+
+Example:
+
+    dtoo.ScrapeAll(map[string]dtoo.ScrapeObject{
+      "posts": dtoo.ScrapeObject{Iterator: ".post", Data: dtoo.Model{"Id": "id", "Title": dtoo.RetrieverModel{Sel: ".post-title", Method: "text"}}},
+      "tags": dtoo.ScrapeObject{Iterator: ".tag-cloud a", Data: "text"},
+    }, doc.Selection, dtoo.ScrapeOptions{})
+*/
+func ScrapeAll(sets map[string]ScrapeObject, s *goquery.Selection, opts ScrapeOptions) (map[string][]interface{}, error) {
+  if sc,err := newScraper(s, nil, opts); err == nil {
+    return sc.scrapeAll(sets, s)
+  } else {
+    return nil, err
+  }
+}
+
+// ScrapeAllFromDocument scrapes several named result sets from an already parsed goquery.Document in a single call.
+// See ScrapeAll for more info.
+func ScrapeAllFromDocument(sets map[string]ScrapeObject, doc *goquery.Document, opts ScrapeOptions) (map[string][]interface{}, error) {
+  if sc,err := newScraper(doc.Selection, doc.Url, opts); err == nil {
+    return sc.scrapeAll(sets, doc.Selection)
+  } else {
+    return nil, err
+  }
+}
+
+// ScrapeAllFromNode scrapes several named result sets from a golang.org/x/net/html node in a single call.
+// See ScrapeAll for more info.
+func ScrapeAllFromNode(sets map[string]ScrapeObject, n *html.Node, opts ScrapeOptions) (map[string][]interface{}, error) {
+  return ScrapeAllFromDocument(sets, goquery.NewDocumentFromNode(n), opts)
+}
+
+// ScrapeAllFromReader parses a document from a file once and scrapes several named result sets from it.
+// See ScrapeAll for more info.
+func ScrapeAllFromReader(sets map[string]ScrapeObject, r io.Reader, opts ScrapeOptions) (map[string][]interface{}, error) {
+  if doc,err := newDocument(r, EMPTYSTRING, opts.Charset); err == nil {
+    return ScrapeAllFromDocument(sets, doc, opts)
+  } else {
+    return nil, err
+  }
+}
+
+// ScrapeAllFromString parses a document from an HTML string once and scrapes several named result sets from it.
+// See ScrapeAll for more info.
+func ScrapeAllFromString(sets map[string]ScrapeObject, html string, opts ScrapeOptions) (map[string][]interface{}, error) {
+  if opts.Charset == EMPTYSTRING {
+    opts.Charset = "utf-8"
+  }
+
+  return ScrapeAllFromReader(sets, strings.NewReader(html), opts)
+}
+
+// ScrapeAllFromUrl fetches a document from a URL once and scrapes several named result sets from it.
+// See ScrapeAll for more info.
+func ScrapeAllFromUrl(sets map[string]ScrapeObject, url string, opts ScrapeOptions) (map[string][]interface{}, error) {
+  if doc,err := fetchDocument(context.Background(), url, opts); err == nil {
+    return ScrapeAllFromDocument(sets, doc, opts)
+  } else {
+    return nil, err
+  }
+}
+
+func (sc *scraper) scrapeAll(sets map[string]ScrapeObject, s *goquery.Selection) (map[string][]interface{}, error) {
+  names := make([]string, 0, len(sets))
+  results := make(map[string][]interface{}, len(sets))
+
+  for name := range(sets) {
+    names = append(names, name)
+  }
+
+  sort.Strings(names)
+
+  for _,name := range(names) {
+    set := sets[name]
+    opts := set.Options
+
+    if !hasIterationOptions(opts) {
+      opts = sc.opts
+    }
+
+    if result,err := sc.scrape(set.Iterator, set.Data, s, opts, sc.rootContext()); err == nil {
+      results[name] = result
+    } else {
+      return results, &SetError{Name: name, Err: err}
+    }
+  }
+
+  return results, nil
+}
+
+// SetError is returned by the ScrapeAll functions when scraping a named result set fails.
+type SetError struct {
+  // The name of the result set that failed.
+  Name string
+  Err error
+}
+
+func (e *SetError) Error() string {
+  return e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *SetError) Unwrap() error {
+  return e.Err
+}
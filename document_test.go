@@ -0,0 +1,83 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "errors"
+  "testing"
+  "golang.org/x/net/html"
+  "github.com/PuerkitoBio/goquery"
+)
+
+func TestScrapeFromNode(t *testing.T) {
+  file, err := os.Open("./fixtures/index.html")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer file.Close()
+
+  node, err := html.Parse(file)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if dates,err := ScrapeFromNode("time", "text", node, ScrapeOptions{Limit: 2}); err == nil {
+    if len(dates) != 2 || dates[1] != "August 25th, 2014" {
+      t.Fatalf("invalid dates retrieved: %v", dates)
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestScrapeAllFromDocument(t *testing.T) {
+  file, err := os.Open("./fixtures/index.html")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer file.Close()
+
+  doc, err := goquery.NewDocumentFromReader(file)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  sets, err := ScrapeAllFromDocument(map[string]ScrapeObject{
+    "title": ScrapeObject{Iterator: "head title", Data: "text"},
+    "posts": ScrapeObject{Iterator: ".post", Data: Model{
+      "Title": RetrieverModel{Sel: ".post-title", Method: "text"},
+    }},
+  }, doc, ScrapeOptions{})
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if len(sets["title"]) != 1 || sets["title"][0] != "Test Page" {
+    t.Fatalf("invalid title set: %v", sets["title"])
+  }
+
+  if len(sets["posts"]) != 6 || sets["posts"][5].(Model)["Title"] != "Some Post 6" {
+    t.Fatalf("invalid posts set: %v", sets["posts"])
+  }
+
+  failing := errors.New("failed")
+  _, err = ScrapeAllFromDocument(map[string]ScrapeObject{
+    "posts": ScrapeObject{Iterator: ".post", Data: func (s *goquery.Selection) (interface{}, error) {
+      return nil, failing
+    }},
+  }, doc, ScrapeOptions{})
+
+  if setErr,ok := err.(*SetError); !ok || setErr.Name != "posts" || !errors.Is(err, failing) {
+    t.Fatalf("invalid error: expected a SetError for %v got %v", "posts", err)
+  }
+}
@@ -0,0 +1,217 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package drift detects when the results of a dtoo data model change shape between runs, usually because the
+target site was redesigned and selectors stopped matching.
+
+Statistics are collected from the results of each run: the number of items scraped and, for every field,
+how often it is filled, the types of its values and the lengths of its slices. A Monitor keeps a baseline
+of these statistics across runs in a local JSON file and reports the fields whose statistics deviate from
+the baseline beyond its Thresholds.
+
+  monitor := &drift.Monitor{File: "posts.drift.json"}
+  posts, err := dtoo.ScrapeFromUrl(".post", model, url)
+
+  if deviations,err := monitor.Check(posts); err == nil {
+    for _,d := range(deviations) {
+      log.Println("drift:", d)
+    }
+  }
+*/
+package drift
+
+import (
+  "reflect"
+  "sort"
+  "github.com/dschnare/dtoo"
+)
+
+// FieldStats holds the statistics of a field over every value observed for it.
+type FieldStats struct {
+  // The number of values observed.
+  Count int
+  // The number of values that were not empty as reported by dtoo.IsEmpty.
+  Filled int
+  // The number of values observed of each type: "string", "number", "bool", "slice", "object" or "nil".
+  Types map[string]int
+  // The number of slices observed and the sum of their lengths.
+  Slices int
+  Elements int
+}
+
+// FillRate returns the fraction of values that were filled, or 0 if no values were observed.
+func (f *FieldStats) FillRate() float64 {
+  return ratio(f.Filled, f.Count)
+}
+
+// TypeShare returns the fraction of values of type typ, or 0 if no values were observed.
+func (f *FieldStats) TypeShare(typ string) float64 {
+  return ratio(f.Types[typ], f.Count)
+}
+
+// AverageLength returns the average length of the slices observed, or 0 if no slices were observed.
+func (f *FieldStats) AverageLength() float64 {
+  return ratio(f.Elements, f.Slices)
+}
+
+/*
+Stats holds the statistics of one or more runs of a data model.
+
+Fields are keyed by path, where "[]" stands for the elements of a slice and keys of a Model are joined
+with ".", i.e. "[]" for the items of the results themselves, "[].Title" for the Title key of every item
+and "[].Comments[].Author" for the Author key of every comment of every item.
+*/
+type Stats struct {
+  // The number of runs collected.
+  Runs int
+  // The number of items scraped, summed over every run.
+  Items int
+  Fields map[string]*FieldStats
+}
+
+// Collect returns the statistics of the results of a single run.
+func Collect(results []interface{}) *Stats {
+  stats := &Stats{Runs: 1, Items: len(results), Fields: map[string]*FieldStats{}}
+
+  for _,result := range(results) {
+    stats.observe("[]", result)
+  }
+
+  return stats
+}
+
+// Add merges the statistics of other into s.
+func (s *Stats) Add(other *Stats) {
+  if s.Fields == nil {
+    s.Fields = map[string]*FieldStats{}
+  }
+
+  s.Runs += other.Runs
+  s.Items += other.Items
+
+  for path,field := range(other.Fields) {
+    f := s.field(path)
+    f.Count += field.Count
+    f.Filled += field.Filled
+    f.Slices += field.Slices
+    f.Elements += field.Elements
+
+    for typ,n := range(field.Types) {
+      f.Types[typ] += n
+    }
+  }
+}
+
+// AverageItems returns the average number of items scraped per run, or 0 if no runs were collected.
+func (s *Stats) AverageItems() float64 {
+  return ratio(s.Items, s.Runs)
+}
+
+// Paths returns the paths of every field in sorted order.
+func (s *Stats) Paths() []string {
+  paths := make([]string, 0, len(s.Fields))
+
+  for path := range(s.Fields) {
+    paths = append(paths, path)
+  }
+
+  sort.Strings(paths)
+  return paths
+}
+
+func (s *Stats) field(path string) *FieldStats {
+  f, exists := s.Fields[path]
+
+  if !exists {
+    f = &FieldStats{Types: map[string]int{}}
+    s.Fields[path] = f
+  }
+
+  return f
+}
+
+// observe records value at path and then every value nested in it.
+func (s *Stats) observe(path string, value interface{}) {
+  f := s.field(path)
+  f.Count++
+
+  if !dtoo.IsEmpty(value) {
+    f.Filled++
+  }
+
+  switch v := value.(type) {
+    case dtoo.Model:
+      f.Types["object"]++
+
+      for key,child := range(v) {
+        s.observe(path + "." + key, child)
+      }
+      return
+    case dtoo.OrderedModel:
+      f.Types["object"]++
+
+      for _,field := range(v) {
+        s.observe(path + "." + field.Key, field.Value)
+      }
+      return
+    case map[string]interface{}:
+      f.Types["object"]++
+
+      for key,child := range(v) {
+        s.observe(path + "." + key, child)
+      }
+      return
+  }
+
+  f.Types[typeName(value)]++
+
+  if value != nil {
+    if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+      f.Slices++
+      f.Elements += rv.Len()
+
+      for i := 0; i < rv.Len(); i++ {
+        s.observe(path + "[]", rv.Index(i).Interface())
+      }
+    }
+  }
+}
+
+func typeName(value interface{}) string {
+  if value == nil {
+    return "nil"
+  }
+
+  switch reflect.ValueOf(value).Kind() {
+    case reflect.String:
+      return "string"
+    case reflect.Bool:
+      return "bool"
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+      reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+      reflect.Float32, reflect.Float64:
+      return "number"
+    case reflect.Slice, reflect.Array:
+      return "slice"
+    case reflect.Map, reflect.Struct:
+      return "object"
+    case reflect.Ptr, reflect.Interface:
+      if reflect.ValueOf(value).IsNil() {
+        return "nil"
+      }
+
+      return typeName(reflect.ValueOf(value).Elem().Interface())
+  }
+
+  return "other"
+}
+
+func ratio(n int, d int) float64 {
+  if d == 0 {
+    return 0
+  }
+
+  return float64(n) / float64(d)
+}
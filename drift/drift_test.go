@@ -0,0 +1,111 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package drift
+
+import (
+  "os"
+  "fmt"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+  "github.com/dschnare/dtoo"
+)
+
+func posts(n int, title string, comments int) []interface{} {
+  results := make([]interface{}, n)
+
+  for i := range(results) {
+    authors := make([]interface{}, comments)
+
+    for j := range(authors) {
+      authors[j] = dtoo.Model{"Author": fmt.Sprint("author ", j)}
+    }
+
+    results[i] = dtoo.Model{"Id": fmt.Sprint(i), "Title": title, "Comments": authors}
+  }
+
+  return results
+}
+
+func TestCollect(t *testing.T) {
+  results := posts(4, "Title", 2)
+  results[0].(dtoo.Model)["Title"] = ""
+  results[1].(dtoo.Model)["Title"] = 1
+  stats := Collect(results)
+
+  if stats.Items != 4 || stats.Runs != 1 {
+    t.Fatalf("invalid counts: %v items in %v runs", stats.Items, stats.Runs)
+  }
+
+  expected := "[[] [].Comments [].Comments[] [].Comments[].Author [].Id [].Title]"
+
+  if fmt.Sprint(stats.Paths()) != expected {
+    t.Fatalf("invalid paths: expected %v got %v", expected, stats.Paths())
+  }
+
+  title := stats.Fields["[].Title"]
+
+  if title.FillRate() != 0.75 || title.TypeShare("string") != 0.75 || title.TypeShare("number") != 0.25 {
+    t.Fatalf("invalid title stats: %+v", title)
+  }
+
+  if comments := stats.Fields["[].Comments"]; comments.AverageLength() != 2 || stats.Fields["[].Comments[].Author"].Count != 8 {
+    t.Fatalf("invalid comment stats: %+v", comments)
+  }
+}
+
+func TestCompare(t *testing.T) {
+  baseline := Collect(posts(10, "Title", 2))
+  baseline.Add(Collect(posts(10, "Title", 2)))
+
+  if deviations := Compare(baseline, Collect(posts(9, "Title", 2)), Thresholds{}); len(deviations) != 0 {
+    t.Fatalf("unexpected deviations: %v", deviations)
+  }
+
+  deviations := Compare(baseline, Collect(posts(4, "", 0)), Thresholds{})
+  expected := "[[]: items 10.00 -> 4.00 [].Comments: fill rate 1.00 -> 0.00 [].Comments: length 2.00 -> 0.00 [].Title: fill rate 1.00 -> 0.00]"
+
+  if fmt.Sprint(deviations) != expected {
+    t.Fatalf("invalid deviations: expected %v got %v", expected, deviations)
+  }
+
+  // A nested field is missing only if its parent had values to observe it in.
+  current := posts(10, "Title", 2)
+
+  for _,result := range(current) {
+    for _,comment := range(result.(dtoo.Model)["Comments"].([]interface{})) {
+      delete(comment.(dtoo.Model), "Author")
+    }
+  }
+
+  deviations = Compare(baseline, Collect(current), Thresholds{})
+  expected = "[[].Comments[]: fill rate 1.00 -> 0.00 [].Comments[].Author: missing]"
+
+  if fmt.Sprint(deviations) != expected {
+    t.Fatalf("invalid deviations: expected %v got %v", expected, deviations)
+  }
+}
+
+func TestMonitor(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-drift")
+  defer os.RemoveAll(dir)
+
+  monitor := &Monitor{File: filepath.Join(dir, "posts.drift.json")}
+
+  for i := 0; i < 2; i++ {
+    if deviations,err := monitor.Check(posts(10, "Title", 1)); err != nil || len(deviations) != 0 {
+      t.Fatalf("unexpected deviations: %v (%v)", deviations, err)
+    }
+  }
+
+  if deviations,err := monitor.Check(posts(10, "", 1)); err != nil || len(deviations) != 1 {
+    t.Fatalf("expected a single deviation: %v (%v)", deviations, err)
+  }
+
+  // Runs with deviations are not added to the baseline.
+  if baseline,err := LoadBaseline(monitor.File); err != nil || baseline.Runs != 2 || baseline.Items != 20 {
+    t.Fatalf("invalid baseline: %+v (%v)", baseline, err)
+  }
+}
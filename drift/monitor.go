@@ -0,0 +1,241 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package drift
+
+import (
+  "os"
+  "fmt"
+  "math"
+  "sort"
+  "strings"
+  "io/ioutil"
+  "encoding/json"
+  "github.com/dschnare/dtoo/internal/fsutil"
+)
+
+// Thresholds specifies how far statistics can deviate from the baseline before they are reported.
+// Zero values are replaced by the corresponding DefaultThresholds value.
+type Thresholds struct {
+  // The largest allowed change of the fill rate of a field, i.e. 0.2 for 20 percentage points.
+  FillRate float64
+  // The largest allowed change of the share of any value type of a field.
+  TypeShare float64
+  // The largest allowed relative change of the number of items scraped and of the average length of
+  // slices, i.e. 0.5 for a 50% change either way.
+  Count float64
+}
+
+// DefaultThresholds are used in place of unset Thresholds.
+var DefaultThresholds = Thresholds{FillRate: 0.2, TypeShare: 0.2, Count: 0.5}
+
+func (t Thresholds) withDefaults() Thresholds {
+  if t.FillRate == 0 {
+    t.FillRate = DefaultThresholds.FillRate
+  }
+  if t.TypeShare == 0 {
+    t.TypeShare = DefaultThresholds.TypeShare
+  }
+  if t.Count == 0 {
+    t.Count = DefaultThresholds.Count
+  }
+
+  return t
+}
+
+// Deviation describes a statistic of a field that deviates from the baseline.
+type Deviation struct {
+  // The path of the field, or "[]" for the items of the results.
+  Path string
+  // The statistic that deviates: "items", "fill rate", "type <name>", "length", "missing" or "new".
+  Metric string
+  Baseline float64
+  Current float64
+}
+
+func (d Deviation) String() string {
+  switch d.Metric {
+    case "missing":
+      return d.Path + ": missing"
+    case "new":
+      return d.Path + ": new"
+  }
+
+  return fmt.Sprintf("%v: %v %.2f -> %.2f", d.Path, d.Metric, d.Baseline, d.Current)
+}
+
+// Compare returns the deviations of current from baseline beyond the thresholds specified, ordered by path.
+// Returns nil if baseline holds no runs.
+func Compare(baseline *Stats, current *Stats, thresholds Thresholds) []Deviation {
+  if baseline.Runs == 0 {
+    return nil
+  }
+
+  t := thresholds.withDefaults()
+  deviations := make([]Deviation, 0)
+
+  if changed(baseline.AverageItems(), current.AverageItems(), t.Count) {
+    deviations = append(deviations, Deviation{"[]", "items", baseline.AverageItems(), current.AverageItems()})
+  }
+
+  paths := baseline.Paths()
+
+  for _,path := range(current.Paths()) {
+    if _,exists := baseline.Fields[path]; !exists {
+      paths = append(paths, path)
+    }
+  }
+
+  for _,path := range(paths) {
+    b, inBaseline := baseline.Fields[path]
+    c, inCurrent := current.Fields[path]
+
+    switch {
+      case !inCurrent:
+        // A field can only be missing if there was something to observe it in.
+        if observable(current, path) {
+          deviations = append(deviations, Deviation{path, "missing", b.FillRate(), 0})
+        }
+        continue
+      case !inBaseline:
+        deviations = append(deviations, Deviation{path, "new", 0, c.FillRate()})
+        continue
+    }
+
+    if math.Abs(b.FillRate() - c.FillRate()) > t.FillRate {
+      deviations = append(deviations, Deviation{path, "fill rate", b.FillRate(), c.FillRate()})
+    }
+
+    for _,typ := range(typeNames(b, c)) {
+      if math.Abs(b.TypeShare(typ) - c.TypeShare(typ)) > t.TypeShare {
+        deviations = append(deviations, Deviation{path, "type " + typ, b.TypeShare(typ), c.TypeShare(typ)})
+      }
+    }
+
+    if b.Slices > 0 && c.Slices > 0 && changed(b.AverageLength(), c.AverageLength(), t.Count) {
+      deviations = append(deviations, Deviation{path, "length", b.AverageLength(), c.AverageLength()})
+    }
+  }
+
+  sortDeviations(deviations)
+  return deviations
+}
+
+// observable reports whether current holds any value path could have been observed in, that is whether
+// the parent of path had elements if path is the elements of a slice, or objects if path is a key.
+func observable(current *Stats, path string) bool {
+  if strings.HasSuffix(path, "[]") {
+    parent := strings.TrimSuffix(path, "[]")
+
+    if parent == "" {
+      return current.Items > 0
+    }
+
+    f, exists := current.Fields[parent]
+    return exists && f.Elements > 0
+  }
+
+  if i := strings.LastIndex(path, "."); i >= 0 {
+    f, exists := current.Fields[path[:i]]
+    return exists && f.Types["object"] > 0
+  }
+
+  return current.Items > 0
+}
+
+// changed reports whether current differs from baseline by more than the relative threshold.
+func changed(baseline float64, current float64, threshold float64) bool {
+  if baseline == 0 {
+    return current != 0
+  }
+
+  return math.Abs(current - baseline) / baseline > threshold
+}
+
+/*
+Monitor compares the statistics of each run of a data model to a baseline persisted in File.
+
+The baseline accumulates the statistics of every run checked without deviations. Runs with deviations are
+not added so a redesign can't slowly become the baseline; delete File to start a new baseline once the
+model has been fixed or the change is expected.
+*/
+type Monitor struct {
+  // The JSON file the baseline is stored in. Created on the first run.
+  File string
+  Thresholds Thresholds
+}
+
+// Check collects the statistics of results, compares them to the baseline and returns the deviations.
+// If there are none then the statistics are added to the baseline and it is saved.
+func (m *Monitor) Check(results []interface{}) ([]Deviation, error) {
+  baseline, err := LoadBaseline(m.File)
+
+  if err != nil {
+    return nil, err
+  }
+
+  current := Collect(results)
+  deviations := Compare(baseline, current, m.Thresholds)
+
+  if len(deviations) > 0 {
+    return deviations, nil
+  }
+
+  baseline.Add(current)
+  return nil, SaveBaseline(m.File, baseline)
+}
+
+// LoadBaseline reads the statistics stored in file. Returns empty statistics if file does not exist.
+func LoadBaseline(file string) (*Stats, error) {
+  stats := &Stats{Fields: map[string]*FieldStats{}}
+  data, err := ioutil.ReadFile(file)
+
+  if os.IsNotExist(err) {
+    return stats, nil
+  } else if err != nil {
+    return nil, err
+  }
+
+  if err := json.Unmarshal(data, stats); err != nil {
+    return nil, err
+  }
+
+  return stats, nil
+}
+
+// SaveBaseline writes stats to file, replacing it atomically.
+func SaveBaseline(file string, stats *Stats) error {
+  data, err := json.MarshalIndent(stats, "", "  ")
+
+  if err != nil {
+    return err
+  }
+
+  return fsutil.WriteFileAtomic(file, data, 0644)
+}
+
+// typeNames returns the value types observed in either a or b in sorted order.
+func typeNames(a *FieldStats, b *FieldStats) []string {
+  names := make([]string, 0)
+  seen := map[string]bool{}
+
+  for _,types := range([]map[string]int{a.Types, b.Types}) {
+    for typ := range(types) {
+      if !seen[typ] {
+        seen[typ] = true
+        names = append(names, typ)
+      }
+    }
+  }
+
+  sort.Strings(names)
+  return names
+}
+
+// sortDeviations orders deviations by path, keeping the order of deviations of the same path.
+func sortDeviations(deviations []Deviation) {
+  sort.SliceStable(deviations, func (i, j int) bool {
+    return deviations[i].Path < deviations[j].Path
+  })
+}
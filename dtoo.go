@@ -6,8 +6,8 @@
 Dtoo exposes an HTML scraper API inspired by the artoo.js Scrape API  https://medialab.github.io/artoo/scrape/.
 
 The dtoo scrape API closely follows artoo's scrape API, but is slightly modified to suit Go.
-The biggest changes are that "scrapeTable" is not implemented and "scrapeOne" is implemented via
-the ScrapeFromXxxWithLimit functions.
+The biggest changes are that "scrapeTable" is not implemented and "scrapeOne" is implemented by
+the ScrapeOneXxx functions, which return ErrNotFound when nothing matches.
 
 The artoo example
 
@@ -20,7 +20,9 @@ written for dtoo would look like this.
 The above example will return a slice of dtoo.Model objects each with the following keys: {id, content}.
 
 The dtoo data model passed to the ScrapeXxx and ScrapeXxxWithLimit functions 
-can be a string, func (s *goquery.Selection) (interface{}, error), dtoo.Model or dtoo.RetrieverModel.
+can be a string, func (s *goquery.Selection) (interface{}, error),
+func (s *goquery.Selection, ctx *dtoo.ScrapeContext) (interface{}, error), dtoo.Model, dtoo.OrderedModel
+or dtoo.RetrieverModel.
 
 Retrieves a slice of post id attributes using a string data model.
 
@@ -101,5 +103,114 @@ The above example could be alternatively achieved by using the recursive Scrape
       },
     },
   }, url)
+
+dtoo.Model keys are evaluated in sorted order and are unordered when serialized. Use dtoo.OrderedModel
+to evaluate and serialize keys in declaration order, which keeps JSON output and CSV columns stable.
+
+  dtoo.ScrapeFromUrl(".post", dtoo.OrderedModel{
+    {Key: "Id", Value: "id"},
+    {Key: "Title", Value: dtoo.RetrieverModel{Sel: ".post-title", Method: "text"}},
+  }, url)
+
+Documents are transcoded to UTF-8 before they are parsed. The encoding is detected from the BOM,
+the Content-Type header and any <meta charset> tag, or can be set explicitly with ScrapeOptions.
+
+  dtoo.ScrapeFromUrlWithOptions(".post", "id", url, dtoo.ScrapeOptions{Charset: "shift_jis"})
+
+Attributes are returned verbatim unless ScrapeOptions.ResolveUrls is set, in which case URL-valued
+attributes such as href and src are resolved against the fetched URL (or ScrapeOptions.BaseUrl) and
+any <base href> tag in the document.
+
+  dtoo.ScrapeFromStringWithOptions("a", "href", html, dtoo.ScrapeOptions{BaseUrl: "http://example.com/", ResolveUrls: true})
+
+Repeat scrapes of the same pages can be served from an on-disk cache by fetching with a CacheTransport.
+
+  client := &http.Client{Transport: &dtoo.CacheTransport{Dir: ".dtoo-cache", TTL: time.Hour}}
+  dtoo.ScrapeFromUrlWithOptions(".post", "id", url, dtoo.ScrapeOptions{Client: client})
+
+Many URLs can be scraped concurrently with ScrapeUrls or ScrapeBatch. Each result is tagged with its
+URL and error, and requests to the same host can be throttled.
+
+  results := dtoo.ScrapeUrls(ctx, urls, ".post", "id", dtoo.BatchOptions{Workers: 8, PerHostDelay: time.Second})
+
+Iterations can be extracted in parallel by setting ScrapeOptions.Workers, and Model keys too with
+ScrapeOptions.ParallelKeys. Results keep their order. The built-in string, RetrieverModel, Model and
+OrderedModel retrievers are safe for concurrent use, but func retrievers and "method" funcs must be
+goroutine-safe when Workers is greater than 1.
+
+  dtoo.ScrapeFromUrlWithOptions(".post", model, url, dtoo.ScrapeOptions{Workers: 8})
+
+Models that are scraped repeatedly can be compiled once. Compile validates the model and compiles all of
+its selectors, and the resulting CompiledModel is safe for concurrent use wherever a data model is accepted.
+
+  posts, err := dtoo.Compile(dtoo.Model{"Id": "id", "Title": dtoo.RetrieverModel{Sel: ".post-title", Method: "text"}})
+
+ValidateModel checks a whole model tree, including the data models of recursive scrapes, without scraping
+any HTML and reports every problem found with its path.
+
+  if err := dtoo.ValidateModel(model); err != nil {
+    log.Fatal(err) // i.e. "Comments.Scrape.Data.Author: unrecognized method "txt""
+  }
+
+Already parsed HTML can be scraped with ScrapeFromDocument and ScrapeFromNode. The ScrapeAllFromXxx
+functions parse a document once and scrape several named result sets from it in a single call.
+
+  sets, err := dtoo.ScrapeAllFromUrl(map[string]dtoo.ScrapeObject{
+    "posts": dtoo.ScrapeObject{Iterator: ".post", Data: "id"},
+    "tags": dtoo.ScrapeObject{Iterator: ".tag-cloud a", Data: "text"},
+  }, url, dtoo.ScrapeOptions{})
+
+ScrapeOptions also controls which matches of the root iterator are extracted with Offset, Limit, Step,
+Reverse, Filter and Not. The same settings can be given to recursive scrapes with ScrapeObject.Options.
+
+  dtoo.ScrapeFromUrlWithOptions(".post", "id", url, dtoo.ScrapeOptions{Offset: 10, Limit: 10, Not: ".sponsored"})
+
+Func retrievers can also take a *ScrapeContext, which holds the iteration index, the path of the value
+being extracted, the document root, its URL and base URL, the keys of the parent Model extracted so far
+and the values from ScrapeOptions.Values.
+
+  "Slug": func (s *goquery.Selection, ctx *dtoo.ScrapeContext) (interface{}, error) {
+    return fmt.Sprintf("%d-%v", ctx.Index, ctx.Parent["Title"]), nil
+  },
+
+Model and OrderedModel keys can be computed from the values extracted for other keys with Computed, so
+derived fields don't have to scrape the DOM again. Computed keys are evaluated after the scraped keys in
+dependency order, and dependency cycles are reported as errors.
+
+  "Logo": dtoo.Computed{Deps: []string{"LogoSmall"}, Func: func (data dtoo.Model) (interface{}, error) {
+    return strings.Replace(data["LogoSmall"].(string), "sm_120", "184x69", 1), nil
+  }},
+
+FirstOf lists candidate data models that are tried in order until one yields a non-empty value, which
+keeps a model working while a site A/B tests its markup. Set ScrapeOptions.OnCandidate to monitor which
+candidate won, i.e. with a CandidateStats.
+
+  stats := &dtoo.CandidateStats{}
+  dtoo.ScrapeFromUrlWithOptions(".product", dtoo.Model{"Price": dtoo.FirstOf{
+    dtoo.RetrieverModel{Sel: ".price--sale", Method: "text"},
+    dtoo.RetrieverModel{Sel: ".price", Method: "text"},
+  }}, url, dtoo.ScrapeOptions{OnCandidate: stats.Record})
+
+Switch picks a data model per item by matching Cases against the item with a selector, an attribute
+value or a predicate func. The Skip data model (or a func retriever returning ErrSkip) drops the item
+from the results of the innermost iteration.
+
+  dtoo.ScrapeFromUrl(".card", dtoo.Switch{
+    Cases: []dtoo.Case{
+      {Is: ".sponsored", Data: dtoo.Skip},
+      {Attr: "data-type", Value: "video", Data: videoModel},
+    },
+    Default: articleModel,
+  }, url)
+
+Data models without funcs can be serialized as JSON and decoded with DecodeModel, so they can be kept in
+files or sent to the server package, which serves scraping as a JSON HTTP API (run it with "dtoo serve").
+
+  model, err := dtoo.DecodeModel([]byte(`{"Id": "id", "Title": {"sel": ".post-title", "method": "text"}}`))
+
+The jobs package runs scrapes on cron-like schedules in-process, writing their results to files or SQLite
+tables and persisting the state of each job, without overlapping runs of the same job.
+
+  dtoo jobs -config jobs.json
 */
 package dtoo
\ No newline at end of file
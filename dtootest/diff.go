@@ -0,0 +1,98 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtootest
+
+import (
+  "fmt"
+  "bytes"
+  "strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// Diff returns a line diff of a and b. Removed lines are prefixed with "-", added lines
+// with "+" and unchanged lines near a change with a space. Returns an empty string if a and b are equal.
+func Diff(a string, b string) string {
+  if a == b {
+    return ""
+  }
+
+  x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+  y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+  // lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+  lcs := make([][]int, len(x) + 1)
+  for i := range(lcs) {
+    lcs[i] = make([]int, len(y) + 1)
+  }
+
+  for i := len(x) - 1; i >= 0; i-- {
+    for j := len(y) - 1; j >= 0; j-- {
+      if x[i] == y[j] {
+        lcs[i][j] = lcs[i + 1][j + 1] + 1
+      } else if lcs[i + 1][j] >= lcs[i][j + 1] {
+        lcs[i][j] = lcs[i + 1][j]
+      } else {
+        lcs[i][j] = lcs[i][j + 1]
+      }
+    }
+  }
+
+  type line struct {
+    op byte
+    text string
+    // The 1-based line number in b (or a for removed lines).
+    num int
+  }
+
+  lines := make([]line, 0, len(x) + len(y))
+  i, j := 0, 0
+
+  for i < len(x) || j < len(y) {
+    if i < len(x) && j < len(y) && x[i] == y[j] {
+      lines = append(lines, line{' ', x[i], j + 1})
+      i++
+      j++
+    } else if i < len(x) && (j == len(y) || lcs[i + 1][j] >= lcs[i][j + 1]) {
+      lines = append(lines, line{'-', x[i], i + 1})
+      i++
+    } else {
+      lines = append(lines, line{'+', y[j], j + 1})
+      j++
+    }
+  }
+
+  // Only show unchanged lines within diffContext lines of a change.
+  show := make([]bool, len(lines))
+  for k,l := range(lines) {
+    if l.op != ' ' {
+      for c := k - diffContext; c <= k + diffContext; c++ {
+        if c >= 0 && c < len(lines) {
+          show[c] = true
+        }
+      }
+    }
+  }
+
+  var buf bytes.Buffer
+  skipped := true
+
+  for k,l := range(lines) {
+    if !show[k] {
+      skipped = true
+      continue
+    }
+
+    if skipped {
+      fmt.Fprintf(&buf, "@@ line %d @@\n", l.num)
+      skipped = false
+    }
+
+    fmt.Fprintf(&buf, "%c %s\n", l.op, l.text)
+  }
+
+  return buf.String()
+}
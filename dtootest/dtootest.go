@@ -0,0 +1,105 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package dtootest provides golden-file testing for dtoo data models.
+
+A golden test scrapes a fixture HTML file with a model and compares the results, serialized as indented
+JSON, to a golden file. When a model or fixture changes intentionally, regenerate the golden files by
+running the tests with the DTOO_UPDATE environment variable set, which also tells tests to record their
+dtoo.ReplayTransport fixtures again (see dtoo.UpdateFixtures).
+
+  func TestPosts(t *testing.T) {
+    dtootest.Golden(t, ".post", dtoo.Model{
+      "Title": dtoo.RetrieverModel{Sel: ".post-title", Method: "text"},
+    }, "fixtures/index.html", "testdata/posts.golden.json")
+  }
+
+  DTOO_UPDATE=1 go test ./...
+
+Packages that prefer a flag can set Update from their own, since dtootest registers no flags.
+*/
+package dtootest
+
+import (
+  "os"
+  "bytes"
+  "testing"
+  "io/ioutil"
+  "encoding/json"
+  "path/filepath"
+  "github.com/dschnare/dtoo"
+)
+
+// Update makes the golden functions (re)write golden files with the current results instead of comparing
+// against them. Defaults to dtoo.UpdateFixtures.
+var Update = dtoo.UpdateFixtures()
+
+// Golden scrapes the fixture HTML file according to the iterator and data model specified and
+// compares the results to the golden JSON file. Reports a line diff on mismatch.
+// If Update is set then the golden file is (re)written instead.
+func Golden(t testing.TB, iterator string, model interface{}, fixture string, golden string) {
+  GoldenWithOptions(t, iterator, model, fixture, golden, dtoo.ScrapeOptions{})
+}
+
+// GoldenWithOptions is like Golden but scrapes the fixture with the options specified.
+func GoldenWithOptions(t testing.TB, iterator string, model interface{}, fixture string, golden string, opts dtoo.ScrapeOptions) {
+  t.Helper()
+
+  file, err := os.Open(fixture)
+
+  if err != nil {
+    t.Fatalf("unable to open fixture: %v", err)
+    return
+  }
+
+  defer file.Close()
+
+  results, err := dtoo.ScrapeFromReaderWithOptions(iterator, model, file, opts)
+
+  if err != nil {
+    t.Fatalf("unable to scrape fixture %v: %v", fixture, err)
+    return
+  }
+
+  CompareGolden(t, results, golden)
+}
+
+// CompareGolden compares the JSON serialization of value to the golden JSON file.
+// Reports a line diff on mismatch. If Update is set then the golden file is (re)written instead.
+func CompareGolden(t testing.TB, value interface{}, golden string) {
+  t.Helper()
+
+  actual, err := json.MarshalIndent(value, "", "  ")
+
+  if err != nil {
+    t.Fatalf("unable to serialize results: %v", err)
+    return
+  }
+
+  actual = append(actual, '\n')
+
+  if Update {
+    if err = os.MkdirAll(filepath.Dir(golden), 0755); err == nil {
+      err = ioutil.WriteFile(golden, actual, 0644)
+    }
+
+    if err != nil {
+      t.Fatalf("unable to update golden file: %v", err)
+    }
+
+    return
+  }
+
+  expected, err := ioutil.ReadFile(golden)
+
+  if err != nil {
+    t.Fatalf("unable to read golden file (run with DTOO_UPDATE=1 to create it): %v", err)
+    return
+  }
+
+  if !bytes.Equal(expected, actual) {
+    t.Errorf("results do not match %v (-golden +actual):\n%v", golden, Diff(string(expected), string(actual)))
+  }
+}
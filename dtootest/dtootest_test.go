@@ -0,0 +1,97 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtootest
+
+import (
+  "os"
+  "fmt"
+  "strings"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+  "github.com/dschnare/dtoo"
+)
+
+var postModel = dtoo.Model{
+  "Date": dtoo.RetrieverModel{Sel: "time", Method: "text"},
+  "Title": dtoo.RetrieverModel{Sel: ".post-title", Method: "text"},
+  "Summary": dtoo.RetrieverModel{Sel: ".post-summary", Method: "text"},
+}
+
+// failRecorder captures failures so mismatches can be tested without failing the test.
+type failRecorder struct {
+  testing.TB
+  failures []string
+}
+
+func (r *failRecorder) Errorf(format string, args ...interface{}) {
+  r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *failRecorder) Fatalf(format string, args ...interface{}) {
+  r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestGolden(t *testing.T) {
+  Golden(t, ".post", postModel, "../fixtures/index.html", "testdata/posts.golden.json")
+}
+
+func TestGoldenMismatch(t *testing.T) {
+  if Update {
+    t.Skip("skipped when updating golden files")
+  }
+
+  r := &failRecorder{TB: t}
+  Golden(r, ".post", dtoo.Model{
+    "Date": dtoo.RetrieverModel{Sel: "time", Method: "text"},
+    "Title": dtoo.RetrieverModel{Sel: ".post-summary", Method: "text"},
+    "Summary": dtoo.RetrieverModel{Sel: ".post-summary", Method: "text"},
+  }, "../fixtures/index.html", "testdata/posts.golden.json")
+
+  if len(r.failures) != 1 {
+    t.Fatalf("failure count invalid: expected %v got %v", 1, len(r.failures))
+  }
+
+  if !strings.Contains(r.failures[0], "-     \"Title\": \"Some Post 2\"") || !strings.Contains(r.failures[0], "+     \"Title\": \"Some post 2 summary.\"") {
+    t.Fatalf("invalid diff reported: %v", r.failures[0])
+  }
+}
+
+func TestUpdate(t *testing.T) {
+  dir, err := ioutil.TempDir("", "dtootest")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer os.RemoveAll(dir)
+  update := Update
+  defer func () {
+    Update = update
+  }()
+
+  golden := filepath.Join(dir, "testdata", "titles.golden.json")
+  Update = true
+  CompareGolden(t, []string{"a", "b"}, golden)
+
+  if data,_ := ioutil.ReadFile(golden); string(data) != "[\n  \"a\",\n  \"b\"\n]\n" {
+    t.Fatalf("invalid golden file: %q", data)
+  }
+
+  Update = false
+  CompareGolden(t, []string{"a", "b"}, golden)
+}
+
+func TestDiff(t *testing.T) {
+  if d := Diff("a\nb\nc\n", "a\nb\nc\n"); d != "" {
+    t.Fatalf("invalid diff for equal input: %q", d)
+  }
+
+  expected := "@@ line 1 @@\n  a\n- b\n+ B\n  c\n+ d\n"
+
+  if d := Diff("a\nb\nc\n", "a\nB\nc\nd\n"); d != expected {
+    t.Fatalf("invalid diff: expected %q got %q", expected, d)
+  }
+}
@@ -0,0 +1,135 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package export
+
+import (
+  "io"
+  "fmt"
+  "encoding/csv"
+)
+
+type csvWriter struct {
+  w *csv.Writer
+  opts Options
+  columns []string
+  // The columns of the header when they are those of the first item, to detect columns it lacks.
+  header map[string]bool
+  // The rows buffered until Close when opts.Buffer is set, so every column of every item is written.
+  rows [][]column
+}
+
+// NewCSVWriter returns a Writer that writes items as flattened CSV rows, preceded by a header row
+// unless opts.NoHeader is set. Rows are streamed with the columns of opts.Columns or else those of the
+// first item, unless opts.Buffer is set.
+func NewCSVWriter(w io.Writer, opts Options) Writer {
+  return &csvWriter{w: csv.NewWriter(w), opts: opts.withDefaults(), columns: opts.Columns}
+}
+
+// NewTSVWriter returns a Writer that writes items as flattened tab separated rows, preceded by a header
+// row unless opts.NoHeader is set. Values containing tabs, quotes or newlines are quoted as in CSV. Rows
+// are streamed as by NewCSVWriter.
+func NewTSVWriter(w io.Writer, opts Options) Writer {
+  cw := csv.NewWriter(w)
+  cw.Comma = '\t'
+  return &csvWriter{w: cw, opts: opts.withDefaults(), columns: opts.Columns}
+}
+
+func (cw *csvWriter) Write(item interface{}) error {
+  columns := flatten(item, cw.opts)
+
+  if cw.columns == nil && cw.opts.Buffer {
+    cw.rows = append(cw.rows, columns)
+    return nil
+  }
+
+  if cw.columns == nil {
+    cw.columns = make([]string, len(columns))
+    cw.header = make(map[string]bool, len(columns))
+
+    for i,c := range(columns) {
+      cw.columns[i] = c.name
+      cw.header[c.name] = true
+    }
+  }
+
+  // Rather than silently dropping the values of later columns, report them.
+  if cw.header != nil {
+    for _,c := range(columns) {
+      if !cw.header[c.name] {
+        return fmt.Errorf("Column %v is not in the header of the first item, set Options.Columns or Options.Buffer", c.name)
+      }
+    }
+  }
+
+  return cw.writeRow(columns)
+}
+
+// writeRow writes the values of columns in the order of the columns written, preceded by the header if
+// it has not been written yet.
+func (cw *csvWriter) writeRow(columns []column) error {
+  if err := cw.writeHeader(); err != nil {
+    return err
+  }
+
+  values := make(map[string]string, len(columns))
+
+  for _,c := range(columns) {
+    values[c.name] = c.value
+  }
+
+  row := make([]string, len(cw.columns))
+
+  for i,name := range(cw.columns) {
+    row[i] = values[name]
+  }
+
+  return cw.w.Write(row)
+}
+
+func (cw *csvWriter) writeHeader() error {
+  if !cw.opts.NoHeader && len(cw.columns) > 0 {
+    if err := cw.w.Write(cw.columns); err != nil {
+      return err
+    }
+
+    cw.opts.NoHeader = true
+  }
+
+  return nil
+}
+
+// Close writes the buffered rows, if any, and flushes the rows written. Buffered rows have the columns
+// of every item in the order they were first seen. If no items were written then only the header of
+// opts.Columns is written, if any.
+func (cw *csvWriter) Close() error {
+  if cw.columns == nil && cw.opts.Buffer {
+    seen := map[string]bool{}
+    cw.columns = make([]string, 0)
+
+    for _,columns := range(cw.rows) {
+      for _,c := range(columns) {
+        if !seen[c.name] {
+          seen[c.name] = true
+          cw.columns = append(cw.columns, c.name)
+        }
+      }
+    }
+
+    for _,columns := range(cw.rows) {
+      if err := cw.writeRow(columns); err != nil {
+        return err
+      }
+    }
+
+    cw.rows = nil
+  }
+
+  if err := cw.writeHeader(); err != nil {
+    return err
+  }
+
+  cw.w.Flush()
+  return cw.w.Error()
+}
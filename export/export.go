@@ -0,0 +1,218 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package export writes the results of dtoo scrapes as JSON, NDJSON, CSV or TSV.
+
+Every format is written by a Writer that streams items as they are written, so results can be exported
+from a plain slice with WriteAll or as they arrive from dtoo.ScrapeBatch with WriteBatch. The columns of
+CSV and TSV rows are those of Options.Columns or else those of the first item, and a later item with
+other columns is an error. Set Options.Buffer to buffer the rows until Close instead, so the header
+holds the columns of every item.
+
+  w := export.NewCSVWriter(os.Stdout, export.Options{})
+  err := export.WriteAll(w, posts)
+
+CSV and TSV rows are flattened: the keys of nested Models become columns named by their dot path (i.e.
+"Author.Name"), the elements of slices of Models are numbered (i.e. "Comments.0.Text") and slices of strings
+and other plain values, such as the results of recursive scrapes with a string data model, are joined
+into a single column. Model keys are ordered by name and OrderedModel keys keep their order.
+*/
+package export
+
+import (
+  "fmt"
+  "sort"
+  "reflect"
+  "strconv"
+  "strings"
+  "encoding/json"
+  "github.com/dschnare/dtoo"
+)
+
+// Writer writes items in a particular format. Close must be called once every item has been written to
+// complete the output. Writers are not safe for concurrent use.
+type Writer interface {
+  Write(item interface{}) error
+  Close() error
+}
+
+// Options specifies how items are flattened into the columns of CSV and TSV rows.
+type Options struct {
+  // The columns written, as flattened paths. Values for columns that are not written are ignored. If
+  // empty then the columns are those of the first item, or of every item if Buffer is set.
+  Columns []string
+  // If true and Columns is empty then rows are buffered until Close, so the header holds the columns of
+  // every item in the order they were first seen. Memory grows with the number of items.
+  Buffer bool
+  // Joins the segments of flattened paths. Defaults to ".".
+  Separator string
+  // Joins the elements of slices of plain values. Defaults to "|".
+  ListSeparator string
+  // If true then no header row is written.
+  NoHeader bool
+}
+
+func (o Options) withDefaults() Options {
+  if o.Separator == "" {
+    o.Separator = "."
+  }
+  if o.ListSeparator == "" {
+    o.ListSeparator = "|"
+  }
+
+  return o
+}
+
+// WriteAll writes every item to w and closes it.
+func WriteAll(w Writer, items []interface{}) error {
+  for _,item := range(items) {
+    if err := w.Write(item); err != nil {
+      return err
+    }
+  }
+
+  return w.Close()
+}
+
+// WriteBatch writes the items of every result received from results to w as they arrive and closes w once
+// results is closed. Results that failed are skipped and the first of their errors is returned, along with
+// any error writing to w. results is always drained.
+func WriteBatch(w Writer, results <-chan dtoo.BatchResult) error {
+  var resultErr, writeErr error
+
+  for result := range(results) {
+    if result.Err != nil {
+      if resultErr == nil {
+        resultErr = fmt.Errorf("%v: %v", result.Url, result.Err)
+      }
+      continue
+    }
+
+    for _,item := range(result.Results) {
+      if writeErr == nil {
+        writeErr = w.Write(item)
+      }
+    }
+  }
+
+  if err := w.Close(); writeErr == nil {
+    writeErr = err
+  }
+
+  if writeErr != nil {
+    return writeErr
+  }
+
+  return resultErr
+}
+
+// column is a flattened value of an item.
+type column struct {
+  name string
+  value string
+}
+
+// flatten returns the columns of item in order. Plain items are written to a single column named "value".
+func flatten(item interface{}, opts Options) []column {
+  columns := make([]column, 0)
+  flattenValue(&columns, nil, item, opts)
+
+  if len(columns) == 1 && columns[0].name == "" {
+    columns[0].name = "value"
+  }
+
+  return columns
+}
+
+func flattenValue(columns *[]column, path []string, value interface{}, opts Options) {
+  name := func (key string) []string {
+    // Copy so sibling paths never share a backing array.
+    return append(append(make([]string, 0, len(path) + 1), path...), key)
+  }
+
+  switch v := value.(type) {
+    case dtoo.Model:
+      keys := make([]string, 0, len(v))
+
+      for key := range(v) {
+        keys = append(keys, key)
+      }
+
+      sort.Strings(keys)
+
+      for _,key := range(keys) {
+        flattenValue(columns, name(key), v[key], opts)
+      }
+      return
+    case dtoo.OrderedModel:
+      for _,field := range(v) {
+        flattenValue(columns, name(field.Key), field.Value, opts)
+      }
+      return
+    case map[string]interface{}:
+      flattenValue(columns, path, dtoo.Model(v), opts)
+      return
+  }
+
+  if value != nil {
+    if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice && !isPlainSlice(rv) {
+      for i := 0; i < rv.Len(); i++ {
+        flattenValue(columns, name(strconv.Itoa(i)), rv.Index(i).Interface(), opts)
+      }
+      return
+    }
+  }
+
+  *columns = append(*columns, column{strings.Join(path, opts.Separator), format(value, opts)})
+}
+
+// isPlainSlice reports whether every element of the slice v is a plain value, i.e. not a Model.
+func isPlainSlice(v reflect.Value) bool {
+  for i := 0; i < v.Len(); i++ {
+    switch v.Index(i).Interface().(type) {
+      case dtoo.Model, dtoo.OrderedModel, map[string]interface{}:
+        return false
+    }
+
+    if e := v.Index(i); e.Kind() == reflect.Interface && !e.IsNil() && e.Elem().Kind() == reflect.Slice {
+      return false
+    }
+  }
+
+  return true
+}
+
+// format returns the text of a plain value. Slices are joined with opts.ListSeparator.
+func format(value interface{}, opts Options) string {
+  if value == nil {
+    return ""
+  }
+
+  switch v := value.(type) {
+    case string:
+      return v
+    case []string:
+      return strings.Join(v, opts.ListSeparator)
+    case fmt.Stringer:
+      return v.String()
+  }
+
+  switch rv := reflect.ValueOf(value); rv.Kind() {
+    case reflect.Slice, reflect.Array:
+      values := make([]string, rv.Len())
+
+      for i := range(values) {
+        values[i] = format(rv.Index(i).Interface(), opts)
+      }
+
+      return strings.Join(values, opts.ListSeparator)
+    case reflect.Map, reflect.Struct:
+      if data,err := json.Marshal(value); err == nil {
+        return string(data)
+      }
+  }
+
+  return fmt.Sprint(value)
+}
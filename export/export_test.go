@@ -0,0 +1,132 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package export
+
+import (
+  "bytes"
+  "errors"
+  "testing"
+  "github.com/dschnare/dtoo"
+)
+
+var posts = []interface{}{
+  dtoo.Model{
+    "Title": "Post, 1",
+    "Author": dtoo.OrderedModel{{Key: "Name", Value: "Ann"}, {Key: "Email", Value: "ann@example.com"}},
+    "Tags": []string{"go", "html"},
+    "Comments": []interface{}{dtoo.Model{"Text": "First"}, dtoo.Model{"Text": "Second"}},
+  },
+  dtoo.Model{
+    "Title": "Post 2",
+    "Author": dtoo.OrderedModel{{Key: "Name", Value: "Bob"}, {Key: "Email", Value: nil}},
+    "Tags": []string{},
+    "Comments": []interface{}{},
+    "Likes": 3,
+  },
+}
+
+func TestWriters(t *testing.T) {
+  tests := []struct {
+    name string
+    writer func (buf *bytes.Buffer) Writer
+    expected string
+  }{
+    {"json", func (buf *bytes.Buffer) Writer { return NewJSONWriter(buf) }, `[
+{"Author":{"Name":"Ann","Email":"ann@example.com"},"Comments":[{"Text":"First"},{"Text":"Second"}],"Tags":["go","html"],"Title":"Post, 1"},
+{"Author":{"Name":"Bob","Email":null},"Comments":[],"Likes":3,"Tags":[],"Title":"Post 2"}
+]
+`},
+    {"ndjson", func (buf *bytes.Buffer) Writer { return NewNDJSONWriter(buf) }, `{"Author":{"Name":"Ann","Email":"ann@example.com"},"Comments":[{"Text":"First"},{"Text":"Second"}],"Tags":["go","html"],"Title":"Post, 1"}
+{"Author":{"Name":"Bob","Email":null},"Comments":[],"Likes":3,"Tags":[],"Title":"Post 2"}
+`},
+    {"csv", func (buf *bytes.Buffer) Writer { return NewCSVWriter(buf, Options{Buffer: true}) }, `Author.Name,Author.Email,Comments.0.Text,Comments.1.Text,Tags,Title,Comments,Likes
+Ann,ann@example.com,First,Second,go|html,"Post, 1",,
+Bob,,,,,Post 2,,3
+`},
+    {"tsv", func (buf *bytes.Buffer) Writer {
+      return NewTSVWriter(buf, Options{Columns: []string{"Title", "Likes", "Author/Name", "Tags"}, Separator: "/", ListSeparator: ";"})
+    }, "Title\tLikes\tAuthor/Name\tTags\nPost, 1\t\tAnn\tgo;html\nPost 2\t3\tBob\t\n"},
+    {"csv without header", func (buf *bytes.Buffer) Writer { return NewCSVWriter(buf, Options{Columns: []string{"Title"}, NoHeader: true}) }, "\"Post, 1\"\nPost 2\n"},
+  }
+
+  for _,test := range(tests) {
+    buf := &bytes.Buffer{}
+
+    if err := WriteAll(test.writer(buf), posts); err != nil {
+      t.Fatal(err)
+    }
+
+    if buf.String() != test.expected {
+      t.Fatalf("invalid %v output: expected\n%v\ngot\n%v", test.name, test.expected, buf.String())
+    }
+  }
+}
+
+func TestWriteLaterColumns(t *testing.T) {
+  buf := &bytes.Buffer{}
+  items := []interface{}{
+    dtoo.Model{"Id": "1", "Comments": []interface{}{}},
+    dtoo.Model{"Id": "2", "Comments": []interface{}{dtoo.Model{"Text": "First"}, dtoo.Model{"Text": "Second"}}},
+  }
+
+  if err := WriteAll(NewCSVWriter(buf, Options{Buffer: true}), items); err != nil {
+    t.Fatal(err)
+  }
+
+  if buf.String() != "Comments,Id,Comments.0.Text,Comments.1.Text\n,1,,\n,2,First,Second\n" {
+    t.Fatalf("invalid output: %q", buf.String())
+  }
+
+  // Streamed rows have the columns of the first item, so later columns are reported rather than dropped.
+  buf.Reset()
+  err := WriteAll(NewCSVWriter(buf, Options{}), items)
+
+  if err == nil || err.Error() != "Column Comments.0.Text is not in the header of the first item, set Options.Columns or Options.Buffer" {
+    t.Fatalf("invalid error: %v", err)
+  }
+
+  buf.Reset()
+
+  if err = WriteAll(NewCSVWriter(buf, Options{}), items[1:]); err != nil || buf.String() != "Comments.0.Text,Comments.1.Text,Id\nFirst,Second,2\n" {
+    t.Fatalf("invalid streamed output: %q (%v)", buf.String(), err)
+  }
+}
+
+func TestWritePlainItems(t *testing.T) {
+  buf := &bytes.Buffer{}
+
+  if err := WriteAll(NewCSVWriter(buf, Options{}), []interface{}{"a", []string{"b", "c"}}); err != nil {
+    t.Fatal(err)
+  }
+
+  if buf.String() != "value\na\nb|c\n" {
+    t.Fatalf("invalid output: %q", buf.String())
+  }
+
+  buf.Reset()
+
+  if err := WriteAll(NewJSONWriter(buf), nil); err != nil || buf.String() != "[]\n" {
+    t.Fatalf("invalid empty output: %q (%v)", buf.String(), err)
+  }
+}
+
+func TestWriteBatch(t *testing.T) {
+  results := make(chan dtoo.BatchResult, 3)
+  results <- dtoo.BatchResult{Url: "http://example.com/1", Results: []interface{}{"a", "b"}}
+  results <- dtoo.BatchResult{Url: "http://example.com/2", Err: errors.New("failed")}
+  results <- dtoo.BatchResult{Url: "http://example.com/3", Results: []interface{}{"c"}}
+  close(results)
+
+  buf := &bytes.Buffer{}
+  err := WriteBatch(NewNDJSONWriter(buf), results)
+
+  if err == nil || err.Error() != "http://example.com/2: failed" {
+    t.Fatalf("invalid error: %v", err)
+  }
+
+  if buf.String() != "\"a\"\n\"b\"\n\"c\"\n" {
+    t.Fatalf("invalid output: %q", buf.String())
+  }
+}
@@ -0,0 +1,73 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package export
+
+import (
+  "io"
+  "encoding/json"
+)
+
+type jsonWriter struct {
+  w io.Writer
+  count int
+}
+
+// NewJSONWriter returns a Writer that writes items as a JSON array, one item per line.
+// OrderedModel keys keep their order.
+func NewJSONWriter(w io.Writer) Writer {
+  return &jsonWriter{w: w}
+}
+
+func (jw *jsonWriter) Write(item interface{}) error {
+  data, err := json.Marshal(item)
+
+  if err != nil {
+    return err
+  }
+
+  prefix := ",\n"
+
+  if jw.count == 0 {
+    prefix = "[\n"
+  }
+
+  jw.count++
+
+  if _,err := io.WriteString(jw.w, prefix); err != nil {
+    return err
+  }
+
+  _,err = jw.w.Write(data)
+  return err
+}
+
+func (jw *jsonWriter) Close() error {
+  end := "\n]\n"
+
+  if jw.count == 0 {
+    end = "[]\n"
+  }
+
+  _,err := io.WriteString(jw.w, end)
+  return err
+}
+
+type ndjsonWriter struct {
+  encoder *json.Encoder
+}
+
+// NewNDJSONWriter returns a Writer that writes items as newline delimited JSON, one item per line.
+// OrderedModel keys keep their order.
+func NewNDJSONWriter(w io.Writer) Writer {
+  return &ndjsonWriter{json.NewEncoder(w)}
+}
+
+func (nw *ndjsonWriter) Write(item interface{}) error {
+  return nw.encoder.Encode(item)
+}
+
+func (nw *ndjsonWriter) Close() error {
+  return nil
+}
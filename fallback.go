@@ -0,0 +1,114 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "errors"
+  "regexp"
+  "sync"
+  "github.com/PuerkitoBio/goquery"
+)
+
+/*
+FirstOf is a data model that lists candidate data models tried in order until one of them yields a
+non-empty value, that is a value other than nil, the empty string or an empty slice or map. Use it to
+keep a model working while a site serves several variants of its markup.
+
+If a candidate fails the next one is tried. If no candidate yields a value then the first error is
+returned, or nil if every candidate yielded an empty value. The ScrapeOptions.OnCandidate callback is
+called with the index of the candidate that won, or -1 if none did.
+
+Example:
+
+    "Price": dtoo.FirstOf{
+      dtoo.RetrieverModel{Sel: ".price--sale", Method: "text"},
+      dtoo.RetrieverModel{Sel: ".price", Method: "text"},
+      dtoo.RetrieverModel{Sel: "[itemprop=price]", Attr: "content"},
+    }
+*/
+type FirstOf []interface{}
+
+func (sc *scraper) extractFirstOf(candidates FirstOf, s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
+  var firstErr error
+
+  for i,candidate := range(candidates) {
+    value, err := sc.extract(candidate, s, ctx)
+
+    if errors.Is(err, ErrSkip) {
+      return nil, err
+    } else if err != nil {
+      if firstErr == nil {
+        firstErr = err
+      }
+    } else if !IsEmpty(value) {
+      sc.candidateWon(ctx, i)
+      return value, nil
+    }
+  }
+
+  sc.candidateWon(ctx, -1)
+  return nil, firstErr
+}
+
+func (sc *scraper) candidateWon(ctx *ScrapeContext, candidate int) {
+  if sc.opts.OnCandidate != nil {
+    sc.opts.OnCandidate(ctx, candidate)
+  }
+}
+
+// itemIndexRegexp matches the iteration indexes in a ScrapeContext path.
+var itemIndexRegexp = regexp.MustCompile(`\[\d+\]`)
+
+/*
+CandidateStats counts which FirstOf candidate won at each path of a model, ignoring iteration indexes
+(i.e. "[3].Price" and "[4].Price" are both counted as "[].Price"). It is safe for concurrent use.
+
+Example:
+
+    stats := &dtoo.CandidateStats{}
+    dtoo.ScrapeFromUrlWithOptions(".product", model, url, dtoo.ScrapeOptions{OnCandidate: stats.Record})
+    log.Println(stats.Counts()["[].Price"]) // i.e. map[0:3 1:22]
+*/
+type CandidateStats struct {
+  mu sync.Mutex
+  counts map[string]map[int]int
+}
+
+// Record counts a win of candidate at the path of ctx. Pass it as ScrapeOptions.OnCandidate.
+func (cs *CandidateStats) Record(ctx *ScrapeContext, candidate int) {
+  path := itemIndexRegexp.ReplaceAllLiteralString(ctx.Path, "[]")
+
+  cs.mu.Lock()
+  defer cs.mu.Unlock()
+
+  if cs.counts == nil {
+    cs.counts = map[string]map[int]int{}
+  }
+
+  if cs.counts[path] == nil {
+    cs.counts[path] = map[int]int{}
+  }
+
+  cs.counts[path][candidate]++
+}
+
+// Counts returns a copy of the number of wins of each candidate keyed by path. Candidate -1 counts the
+// times no candidate yielded a value.
+func (cs *CandidateStats) Counts() map[string]map[int]int {
+  cs.mu.Lock()
+  defer cs.mu.Unlock()
+
+  counts := make(map[string]map[int]int, len(cs.counts))
+
+  for path,wins := range(cs.counts) {
+    counts[path] = make(map[int]int, len(wins))
+
+    for candidate,n := range(wins) {
+      counts[path][candidate] = n
+    }
+  }
+
+  return counts
+}
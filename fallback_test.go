@@ -0,0 +1,58 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "errors"
+  "fmt"
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+const fallbackHtml = `<ul>
+<li><span class="price--sale">$5</span><span class="price">$10</span></li>
+<li><span class="price">$20</span></li>
+<li><meta itemprop="price" content="$30"></li>
+<li><span class="price"></span></li>
+</ul>`
+
+func TestFirstOf(t *testing.T) {
+  model := Model{"Price": FirstOf{
+    RetrieverModel{Sel: ".price--sale", Method: "text"},
+    RetrieverModel{Sel: ".price", Method: "text"},
+    RetrieverModel{Sel: "[itemprop=price]", Attr: "content"},
+  }}
+
+  stats := &CandidateStats{}
+  items, err := ScrapeFromStringWithOptions("li", model, fallbackHtml, ScrapeOptions{OnCandidate: stats.Record})
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if fmt.Sprint(items) != "[map[Price:$5] map[Price:$20] map[Price:$30] map[Price:<nil>]]" {
+    t.Fatalf("invalid prices: %v", items)
+  }
+
+  if counts := fmt.Sprint(stats.Counts()); counts != "map[[].Price:map[-1:1 0:1 1:1 2:1]]" {
+    t.Fatalf("invalid candidate counts: %v", counts)
+  }
+}
+
+func TestFirstOfError(t *testing.T) {
+  failing := func (s *goquery.Selection) (interface{}, error) {
+    return nil, errors.New("failed")
+  }
+
+  if _,err := ScrapeFromString("li", FirstOf{failing, "missing"}, fallbackHtml); err == nil || err.Error() != "failed" {
+    t.Fatalf("invalid error: expected %v got %v", "failed", err)
+  }
+
+  if err := ValidateModel(Model{"Price": FirstOf{RetrieverModel{Sel: ".price[", Method: "text"}}, "Empty": FirstOf{}}); err == nil {
+    t.Fatal("expected validation to fail")
+  } else if err.Error() != `Empty: empty FirstOf; Price.FirstOf[0]: invalid selector ".price[": expected identifier, found EOF instead` {
+    t.Fatalf("invalid validation error: %v", err)
+  }
+}
@@ -0,0 +1,42 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+// Package fsutil holds file system helpers shared by the dtoo packages.
+package fsutil
+
+import (
+  "os"
+  "path/filepath"
+)
+
+// WriteFileAtomic writes data to a uniquely named temporary file in the directory of path and then
+// renames it to path, so readers never see a partially written file and concurrent writers never
+// clobber each other's temporary file. The temporary file is removed if writing it fails.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+  file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path) + ".tmp*")
+
+  if err != nil {
+    return err
+  }
+
+  tmp := file.Name()
+
+  if _,err = file.Write(data); err == nil {
+    err = file.Chmod(perm)
+  }
+
+  if closeErr := file.Close(); err == nil {
+    err = closeErr
+  }
+
+  if err == nil {
+    err = os.Rename(tmp, path)
+  }
+
+  if err != nil {
+    os.Remove(tmp)
+  }
+
+  return err
+}
@@ -0,0 +1,49 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package fsutil
+
+import (
+  "os"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+  dir, err := ioutil.TempDir("", "fsutil")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer os.RemoveAll(dir)
+  path := filepath.Join(dir, "state.json")
+
+  for _,data := range([]string{"first", "second"}) {
+    if err = WriteFileAtomic(path, []byte(data), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  if data,_ := ioutil.ReadFile(path); string(data) != "second" {
+    t.Fatalf("invalid contents: %q", data)
+  }
+
+  if info,_ := os.Stat(path); info.Mode().Perm() != 0644 {
+    t.Fatalf("invalid mode: %v", info.Mode())
+  }
+
+  // No temporary file is left behind when the rename fails.
+  sub := filepath.Join(dir, "sub")
+  os.MkdirAll(filepath.Join(sub, "child"), 0755)
+
+  if err = WriteFileAtomic(sub, []byte("data"), 0644); err == nil {
+    t.Fatal("expected an error replacing a directory")
+  }
+
+  if entries,_ := ioutil.ReadDir(dir); len(entries) != 2 {
+    t.Fatalf("expected only state.json and sub got %v entries", len(entries))
+  }
+}
@@ -0,0 +1,54 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "github.com/PuerkitoBio/goquery"
+)
+
+// iterate returns the matches in items selected by the iteration settings of opts, in iteration order.
+func (sc *scraper) iterate(items *goquery.Selection, opts ScrapeOptions) []*goquery.Selection {
+  if opts.Not != EMPTYSTRING {
+    if m := sc.matcher(opts.Not); m != nil {
+      items = items.NotMatcher(m)
+    } else {
+      items = items.Not(opts.Not)
+    }
+  }
+
+  if opts.Filter != nil {
+    items = items.FilterFunction(func (i int, s *goquery.Selection) bool {
+      return opts.Filter(s)
+    })
+  }
+
+  n := items.Length()
+  step := int(opts.Step)
+
+  if step < 1 {
+    step = 1
+  }
+
+  selected := make([]*goquery.Selection, 0)
+
+  for i := int(opts.Offset); i < n; i += step {
+    if opts.Limit > 0 && uint(len(selected)) == opts.Limit {
+      break
+    }
+
+    if opts.Reverse {
+      selected = append(selected, items.Eq(n - 1 - i))
+    } else {
+      selected = append(selected, items.Eq(i))
+    }
+  }
+
+  return selected
+}
+
+// hasIterationOptions reports whether any of the iteration settings of opts are set.
+func hasIterationOptions(opts ScrapeOptions) bool {
+  return opts.Offset > 0 || opts.Limit > 0 || opts.Step > 0 || opts.Reverse || opts.Filter != nil || opts.Not != EMPTYSTRING
+}
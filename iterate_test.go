@@ -0,0 +1,56 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "fmt"
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+func TestScrapeIterationOptions(t *testing.T) {
+  even := func (s *goquery.Selection) bool {
+    id, _ := s.Attr("id")
+    return id[0] % 2 == 0
+  }
+
+  tests := []struct {
+    opts ScrapeOptions
+    expected string
+  }{
+    {ScrapeOptions{}, "[0 1 2 3 4 5 6 7 8 9]"},
+    {ScrapeOptions{Offset: 7}, "[7 8 9]"},
+    {ScrapeOptions{Offset: 2, Limit: 3}, "[2 3 4]"},
+    {ScrapeOptions{Step: 3}, "[0 3 6 9]"},
+    {ScrapeOptions{Offset: 1, Step: 4}, "[1 5 9]"},
+    {ScrapeOptions{Reverse: true, Limit: 3}, "[9 8 7]"},
+    {ScrapeOptions{Filter: even}, "[0 2 4 6 8]"},
+    {ScrapeOptions{Not: "#3, #4"}, "[0 1 2 5 6 7 8 9]"},
+    {ScrapeOptions{Filter: even, Not: "#0", Reverse: true, Offset: 1, Step: 2}, "[6 2]"},
+    {ScrapeOptions{Offset: 20}, "[]"},
+  }
+
+  for _,test := range(tests) {
+    if ids,err := ScrapeFromStringWithOptions("li", "id", numberedList(10), test.opts); err == nil {
+      if fmt.Sprint(ids) != test.expected {
+        t.Fatalf("invalid items for %+v: expected %v got %v", test.opts, test.expected, ids)
+      }
+    } else {
+      t.Fatal(err)
+    }
+  }
+}
+
+func TestScrapeObjectIterationOptions(t *testing.T) {
+  model := RetrieverModel{Scrape: ScrapeObject{Iterator: "li", Data: "id", Options: ScrapeOptions{Reverse: true, Step: 2}}}
+
+  if lists,err := ScrapeFromString("ul", model, numberedList(5)); err == nil {
+    if fmt.Sprint(lists) != "[[4 2 0]]" {
+      t.Fatalf("invalid items: expected %v got %v", "[[4 2 0]]", lists)
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
@@ -0,0 +1,136 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+  "io"
+  "fmt"
+  "time"
+  "errors"
+  "encoding/json"
+  "github.com/dschnare/dtoo"
+  "github.com/dschnare/dtoo/export"
+  "github.com/dschnare/dtoo/sqlite"
+)
+
+// Config is a set of jobs and the file their state is kept in.
+type Config struct {
+  State string
+  Jobs []Job
+}
+
+type configFile struct {
+  State string `json:"state"`
+  Jobs []jobConfig `json:"jobs"`
+}
+
+type jobConfig struct {
+  Name string `json:"name"`
+  Url string `json:"url"`
+  Iterator string `json:"iterator"`
+  Model json.RawMessage `json:"model"`
+  Options dtoo.JSONOptions `json:"options"`
+  Schedule string `json:"schedule"`
+  Timeout string `json:"timeout"`
+  Sink sinkConfig `json:"sink"`
+}
+
+type sinkConfig struct {
+  Type string `json:"type"`
+  Path string `json:"path"`
+  Columns []string `json:"columns"`
+  Buffer bool `json:"buffer"`
+  Table string `json:"table"`
+  Key string `json:"key"`
+  ChildTables bool `json:"childTables"`
+}
+
+// LoadConfig reads a JSON config of jobs. Models are serialized as described by dtoo.DecodeModel and
+// options as dtoo.JSONOptions. The sink type is "json", "ndjson", "csv" or "tsv" for a FileSink or
+// "sqlite" for a SQLiteSink. Columns and buffer are the export.Options of csv and tsv sinks. Timeout is
+// optional.
+//
+//     {
+//       "state": "jobs-state.json",
+//       "jobs": [{
+//         "name": "posts",
+//         "url": "https://example.com/blog",
+//         "iterator": ".post",
+//         "model": {"Id": "id", "Title": {"sel": ".post-title", "method": "text"}},
+//         "schedule": "*/30 * * * *",
+//         "timeout": "1m",
+//         "sink": {"type": "sqlite", "path": "posts.db", "table": "posts", "key": "Id"}
+//       }]
+//     }
+func LoadConfig(r io.Reader) (Config, error) {
+  file := configFile{}
+  decoder := json.NewDecoder(r)
+  decoder.DisallowUnknownFields()
+
+  if err := decoder.Decode(&file); err != nil {
+    return Config{}, err
+  }
+
+  config := Config{State: file.State, Jobs: make([]Job, len(file.Jobs))}
+  names := map[string]bool{}
+
+  for i,jc := range(file.Jobs) {
+    if jc.Name == "" {
+      return config, fmt.Errorf("Job %d has no name", i)
+    }
+
+    if names[jc.Name] {
+      return config, errors.New("Duplicate job " + jc.Name)
+    }
+
+    names[jc.Name] = true
+
+    if job,err := jc.job(); err == nil {
+      config.Jobs[i] = job
+    } else {
+      return config, errors.New(jc.Name + ": " + err.Error())
+    }
+  }
+
+  return config, nil
+}
+
+func (jc jobConfig) job() (Job, error) {
+  job := Job{Name: jc.Name, Url: jc.Url, Iterator: jc.Iterator, Options: jc.Options.ScrapeOptions()}
+  var err error
+
+  if jc.Url == "" {
+    return job, errors.New("No url")
+  }
+
+  if job.Model,err = dtoo.DecodeModel(jc.Model); err != nil {
+    return job, err
+  }
+
+  if job.Schedule,err = ParseSchedule(jc.Schedule); err != nil {
+    return job, err
+  }
+
+  if jc.Timeout != "" {
+    if job.Timeout,err = time.ParseDuration(jc.Timeout); err != nil {
+      return job, err
+    }
+  }
+
+  if jc.Sink.Path == "" {
+    return job, errors.New("Sink has no path")
+  }
+
+  switch jc.Sink.Type {
+    case "json", "ndjson", "csv", "tsv":
+      job.Sink = FileSink{Path: jc.Sink.Path, Format: jc.Sink.Type, Options: export.Options{Columns: jc.Sink.Columns, Buffer: jc.Sink.Buffer}}
+    case "sqlite":
+      job.Sink = SQLiteSink{Path: jc.Sink.Path, Options: sqlite.Options{Table: jc.Sink.Table, Key: jc.Sink.Key, ChildTables: jc.Sink.ChildTables}}
+    default:
+      return job, errors.New("Unsupported sink type " + jc.Sink.Type)
+  }
+
+  return job, nil
+}
@@ -0,0 +1,300 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package jobs runs dtoo scrapes on cron-like schedules in-process and stores their results in a sink,
+replacing cron and shell glue.
+
+Each Job scrapes a URL with a data model on a Schedule and writes the results to a Sink, such as a
+FileSink or a SQLiteSink. A Runner runs the jobs and keeps the state of each, i.e. when it last ran and
+succeeded and its recent failures, in a JSON file so it survives restarts. A job is never run while its
+previous run is still going. The overlapping run is skipped and counted instead.
+
+	file, err := os.Open("jobs.json")
+	config, err := jobs.LoadConfig(file)
+	runner, err := jobs.NewRunner(config)
+	err = runner.Run(ctx)
+
+The dtoo command runs the jobs of a config with "dtoo jobs".
+*/
+package jobs
+
+import (
+  "os"
+  "sync"
+  "time"
+  "errors"
+  "context"
+  "io/ioutil"
+  "encoding/json"
+  "github.com/dschnare/dtoo"
+  "github.com/dschnare/dtoo/internal/fsutil"
+)
+
+// MaxFailures is the number of recent failures kept in the state of a job.
+const MaxFailures = 20
+
+// ErrRunning is returned when a job is run while its previous run is still going.
+var ErrRunning = errors.New("Job is already running")
+
+// Job scrapes a URL on a schedule and writes the results to a sink.
+type Job struct {
+  Name string
+  Url string
+  Iterator string
+  Model interface{}
+  Options dtoo.ScrapeOptions
+  Schedule Schedule
+  // The time a run may take to scrape before its results are written. No limit if 0. The sink isn't
+  // bounded by it, so a slow sink keeps the job running and its later runs are skipped until it returns.
+  Timeout time.Duration
+  Sink Sink
+}
+
+// Failure is a failed run of a job.
+type Failure struct {
+  Time time.Time `json:"time"`
+  Error string `json:"error"`
+}
+
+// JobState is the state of a job across runs.
+type JobState struct {
+  LastRun time.Time `json:"lastRun"`
+  LastSuccess time.Time `json:"lastSuccess"`
+  // The error of the last run, or empty if it succeeded.
+  LastError string `json:"lastError,omitempty"`
+  Runs int `json:"runs"`
+  // The number of items the last successful run scraped.
+  Items int `json:"items"`
+  // The number of failed runs since the last successful one.
+  ConsecutiveFailures int `json:"consecutiveFailures"`
+  // The number of runs skipped because the previous run was still going.
+  Skipped int `json:"skipped"`
+  // The most recent failures, oldest first.
+  Failures []Failure `json:"failures,omitempty"`
+}
+
+// Runner runs jobs on their schedules and persists their state. It is safe for concurrent use.
+type Runner struct {
+  // Called after every run of a job with its error, if set. Also called with the error of saving the
+  // state when a skipped run couldn't be recorded. Useful for logging.
+  OnRun func(name string, err error)
+  jobs []Job
+  stateFile string
+  mutex sync.Mutex
+  state map[string]*JobState
+  running map[string]bool
+  wg sync.WaitGroup
+}
+
+// NewRunner returns a Runner for the jobs of config, loading their state from config.State if it exists.
+// If config.State is empty then the state is not persisted.
+func NewRunner(config Config) (*Runner, error) {
+  r := &Runner{jobs: config.Jobs, stateFile: config.State, state: map[string]*JobState{}, running: map[string]bool{}}
+
+  if r.stateFile != "" {
+    data, err := ioutil.ReadFile(r.stateFile)
+
+    if err != nil && !os.IsNotExist(err) {
+      return nil, err
+    }
+
+    if err == nil {
+      if err = json.Unmarshal(data, &r.state); err != nil {
+        return nil, err
+      }
+    }
+  }
+
+  for _,job := range(r.jobs) {
+    if r.state[job.Name] == nil {
+      r.state[job.Name] = &JobState{}
+    }
+  }
+
+  return r, nil
+}
+
+// State returns a copy of the state of every job by name.
+func (r *Runner) State() map[string]JobState {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+
+  state := make(map[string]JobState, len(r.state))
+
+  for name,js := range(r.state) {
+    copied := *js
+    copied.Failures = append([]Failure(nil), js.Failures...)
+    state[name] = copied
+  }
+
+  return state
+}
+
+/*
+Run runs every job on its schedule until ctx is done, then waits for the running jobs to stop and
+returns. Runs missed while the runner was not running are not made up.
+
+Example:
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := runner.Run(ctx)
+*/
+func (r *Runner) Run(ctx context.Context) error {
+  now := time.Now()
+  next := make([]time.Time, len(r.jobs))
+
+  for i,job := range(r.jobs) {
+    next[i] = job.Schedule.Next(now)
+  }
+
+  defer r.wg.Wait()
+
+  for {
+    var earliest time.Time
+
+    for _,t := range(next) {
+      if !t.IsZero() && (earliest.IsZero() || t.Before(earliest)) {
+        earliest = t
+      }
+    }
+
+    // No job runs again.
+    if earliest.IsZero() {
+      return nil
+    }
+
+    timer := time.NewTimer(time.Until(earliest))
+
+    select {
+      case <-ctx.Done():
+        timer.Stop()
+        return nil
+      case now = <-timer.C:
+    }
+
+    for i,job := range(r.jobs) {
+      if !next[i].IsZero() && !next[i].After(now) {
+        next[i] = job.Schedule.Next(now)
+
+        if r.start(job) {
+          go r.run(ctx, job)
+        }
+      }
+    }
+  }
+}
+
+// RunJob runs the job with the specified name now, regardless of its schedule, and returns the error of
+// the run. Returns ErrRunning if the job is already running.
+func (r *Runner) RunJob(ctx context.Context, name string) error {
+  for _,job := range(r.jobs) {
+    if job.Name == name {
+      if !r.start(job) {
+        return ErrRunning
+      }
+
+      return r.run(ctx, job)
+    }
+  }
+
+  return errors.New("Unknown job " + name)
+}
+
+// start marks job as running and returns true, or counts a skipped run and returns false if it is
+// already running.
+func (r *Runner) start(job Job) bool {
+  r.mutex.Lock()
+
+  if r.running[job.Name] {
+    r.state[job.Name].Skipped++
+    err := r.save()
+    r.mutex.Unlock()
+
+    if err != nil && r.OnRun != nil {
+      r.OnRun(job.Name, err)
+    }
+
+    return false
+  }
+
+  r.running[job.Name] = true
+  r.wg.Add(1)
+  r.mutex.Unlock()
+  return true
+}
+
+// run runs a job marked as running by start and records the outcome in its state.
+func (r *Runner) run(ctx context.Context, job Job) error {
+  defer r.wg.Done()
+  started := time.Now()
+
+  if job.Timeout > 0 {
+    var cancel context.CancelFunc
+    ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+    defer cancel()
+  }
+
+  results, err := dtoo.ScrapeFromUrlWithContext(ctx, job.Iterator, job.Model, job.Url, job.Options)
+
+  // A sink can't be cancelled so it isn't started once the deadline has passed. A sink that has started
+  // is allowed to finish, and its results count as a success.
+  if err == nil {
+    err = ctx.Err()
+  }
+
+  if err == nil {
+    err = job.Sink.Write(results)
+  }
+
+  r.mutex.Lock()
+  js := r.state[job.Name]
+  js.LastRun = started
+  js.Runs++
+
+  if err == nil {
+    js.LastSuccess = started
+    js.LastError = ""
+    js.Items = len(results)
+    js.ConsecutiveFailures = 0
+  } else {
+    js.LastError = err.Error()
+    js.ConsecutiveFailures++
+    js.Failures = append(js.Failures, Failure{Time: started, Error: err.Error()})
+
+    if len(js.Failures) > MaxFailures {
+      js.Failures = js.Failures[len(js.Failures) - MaxFailures:]
+    }
+  }
+
+  saveErr := r.save()
+  delete(r.running, job.Name)
+  r.mutex.Unlock()
+
+  if err == nil {
+    err = saveErr
+  }
+
+  if r.OnRun != nil {
+    r.OnRun(job.Name, err)
+  }
+
+  return err
+}
+
+// save writes the state to the state file, replacing it atomically. The mutex must be held.
+func (r *Runner) save() error {
+  if r.stateFile == "" {
+    return nil
+  }
+
+  data, err := json.MarshalIndent(r.state, "", "  ")
+
+  if err != nil {
+    return err
+  }
+
+  return fsutil.WriteFileAtomic(r.stateFile, data, 0644)
+}
@@ -0,0 +1,286 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+  "os"
+  "fmt"
+  "time"
+  "strings"
+  "testing"
+  "context"
+  "net/http"
+  "io/ioutil"
+  "path/filepath"
+  "net/http/httptest"
+)
+
+func TestParseSchedule(t *testing.T) {
+  // Wednesday.
+  from := time.Date(2026, 10, 14, 10, 7, 30, 0, time.UTC)
+  tests := []struct {
+    spec string
+    expected string
+  }{
+    {"*/15 * * * *", "2026-10-14 10:15"},
+    {"5/20 * * * *", "2026-10-14 10:25"},
+    {"0 9-17 * * 1-5", "2026-10-14 11:00"},
+    {"30 2 * * 0", "2026-10-18 02:30"},
+    {"30 2 * * 7", "2026-10-18 02:30"},
+    {"0 0 1,15 * *", "2026-10-15 00:00"},
+    {"0 0 1 * 1", "2026-10-19 00:00"},
+    {"0 0 29 2 *", "2028-02-29 00:00"},
+    {"@daily", "2026-10-15 00:00"},
+    {"@every 90s", "2026-10-14 10:09"},
+  }
+
+  for _,test := range(tests) {
+    schedule, err := ParseSchedule(test.spec)
+
+    if err != nil {
+      t.Fatal(err)
+    }
+
+    if next := schedule.Next(from).Format("2006-01-02 15:04"); next != test.expected {
+      t.Fatalf("invalid next run for %v: expected %v got %v", test.spec, test.expected, next)
+    }
+  }
+
+  if schedule,_ := ParseSchedule("0 0 30 2 *"); !schedule.Next(from).IsZero() {
+    t.Fatal("expected a schedule that never runs")
+  }
+
+  for _,spec := range([]string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@every -1s", "@often"}) {
+    if _,err := ParseSchedule(spec); err == nil {
+      t.Fatalf("expected an error for %v", spec)
+    }
+  }
+}
+
+func TestLoadConfig(t *testing.T) {
+  config, err := LoadConfig(strings.NewReader(`{"state": "state.json", "jobs": [
+    {"name": "posts", "url": "http://localhost/", "iterator": ".post", "model": "id", "schedule": "@hourly", "timeout": "1m",
+      "sink": {"type": "sqlite", "path": "posts.db", "table": "posts", "key": "Id"}}
+  ]}`))
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if job := config.Jobs[0]; config.State != "state.json" || job.Model != "id" || job.Timeout != time.Minute || fmt.Sprint(job.Sink) != "{posts.db {posts Id false}}" {
+    t.Fatalf("invalid config: %v", config)
+  }
+
+  tests := []struct {
+    jobs string
+    expected string
+  }{
+    {`{"url": "http://localhost/", "model": "id", "schedule": "@hourly"}`, "Job 0 has no name"},
+    {`{"name": "a", "url": "http://localhost/", "model": "id", "schedule": "@hourly", "sink": {"type": "xml", "path": "a.xml"}}`, "a: Unsupported sink type xml"},
+    {`{"name": "a", "url": "http://localhost/", "model": "id", "schedule": "hourly"}`, `a: Invalid schedule "hourly": expected 5 fields`},
+    {`{"name": "a", "url": "http://localhost/", "model": 1}`, "a: model: cannot decode a number as a data model"},
+  }
+
+  for _,test := range(tests) {
+    if _,err := LoadConfig(strings.NewReader(`{"jobs": [` + test.jobs + `]}`)); err == nil || err.Error() != test.expected {
+      t.Fatalf("invalid error: expected %v got %v", test.expected, err)
+    }
+  }
+}
+
+func TestRunJob(t *testing.T) {
+  dir, err := ioutil.TempDir("", "jobs")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer os.RemoveAll(dir)
+
+  site := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    fmt.Fprint(w, `<p id="1"></p><p id="2"></p>`)
+  }))
+  defer site.Close()
+
+  output := filepath.Join(dir, "ids.ndjson")
+  schedule, _ := ParseSchedule("@daily")
+  config := Config{
+    State: filepath.Join(dir, "state.json"),
+    Jobs: []Job{{Name: "ids", Url: site.URL, Iterator: "p", Model: "id", Schedule: schedule, Sink: FileSink{Path: output, Format: "ndjson"}}},
+  }
+
+  runner, err := NewRunner(config)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  for i := 0; i < 2; i++ {
+    if err = runner.RunJob(context.Background(), "ids"); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  if data,_ := ioutil.ReadFile(output); string(data) != "\"1\"\n\"2\"\n\"1\"\n\"2\"\n" {
+    t.Fatalf("invalid output: %q", data)
+  }
+
+  // A sink that fails records a failure.
+  runner.jobs[0].Sink = FileSink{Path: filepath.Join(dir, "ids.xml"), Format: "xml"}
+
+  if err = runner.RunJob(context.Background(), "ids"); err == nil || err.Error() != "Unsupported format xml" {
+    t.Fatalf("invalid error: %v", err)
+  }
+
+  if err = runner.RunJob(context.Background(), "posts"); err == nil || err.Error() != "Unknown job posts" {
+    t.Fatalf("invalid error: %v", err)
+  }
+
+  // The state is reloaded by a new runner.
+  if runner,err = NewRunner(config); err != nil {
+    t.Fatal(err)
+  }
+
+  state := runner.State()["ids"]
+
+  if state.Runs != 3 || state.Items != 2 || state.ConsecutiveFailures != 1 || state.LastError != "Unsupported format xml" || len(state.Failures) != 1 || state.LastSuccess.IsZero() {
+    t.Fatalf("invalid state: %+v", state)
+  }
+}
+
+func TestNoOverlap(t *testing.T) {
+  release := make(chan bool)
+  requests := make(chan bool, 10)
+  site := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    requests <- true
+    <-release
+    fmt.Fprint(w, `<p id="1"></p>`)
+  }))
+  defer site.Close()
+
+  schedule, _ := ParseSchedule("@every 10ms")
+  runner, err := NewRunner(Config{Jobs: []Job{{Name: "slow", Url: site.URL, Iterator: "p", Model: "id", Schedule: schedule, Sink: FileSink{Path: os.DevNull, Format: "ndjson"}}}})
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  ctx, cancel := context.WithCancel(context.Background())
+  done := make(chan error)
+
+  go func () {
+    done <- runner.Run(ctx)
+  }()
+
+  <-requests
+
+  if err = runner.RunJob(ctx, "slow"); err != ErrRunning {
+    t.Fatalf("expected ErrRunning got %v", err)
+  }
+
+  // Let a few more runs come due while the first is still going.
+  for deadline := time.Now().Add(5 * time.Second); runner.State()["slow"].Skipped < 3; {
+    if time.Now().After(deadline) {
+      t.Fatalf("expected skipped runs: %+v", runner.State()["slow"])
+    }
+
+    time.Sleep(time.Millisecond)
+  }
+
+  close(release)
+  cancel()
+
+  if err = <-done; err != nil {
+    t.Fatal(err)
+  }
+
+  if state := runner.State()["slow"]; state.Runs < 1 || state.Skipped < 3 {
+    t.Fatalf("invalid state: %+v", state)
+  }
+}
+
+func TestSkippedSaveError(t *testing.T) {
+  release := make(chan bool)
+  requests := make(chan bool, 10)
+  site := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    requests <- true
+    <-release
+    fmt.Fprint(w, `<p id="1"></p>`)
+  }))
+  defer site.Close()
+
+  dir, err := ioutil.TempDir("", "jobs")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer os.RemoveAll(dir)
+
+  runner, err := NewRunner(Config{
+    State: filepath.Join(dir, "state", "state.json"),
+    Jobs: []Job{{Name: "slow", Url: site.URL, Iterator: "p", Model: "id", Sink: FileSink{Path: os.DevNull, Format: "ndjson"}}},
+  })
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  var reported []error
+  runner.OnRun = func (name string, err error) {
+    reported = append(reported, err)
+  }
+
+  done := make(chan error)
+
+  go func () {
+    done <- runner.RunJob(context.Background(), "slow")
+  }()
+
+  <-requests
+
+  // The state directory doesn't exist so the skipped run can't be saved.
+  if err = runner.RunJob(context.Background(), "slow"); err != ErrRunning {
+    t.Fatalf("expected ErrRunning got %v", err)
+  }
+
+  if len(reported) != 1 || reported[0] == nil {
+    t.Fatalf("expected the save error to be reported got %v", reported)
+  }
+
+  close(release)
+  <-done
+}
+
+type slowSink struct {
+  delay time.Duration
+}
+
+func (ss slowSink) Write(results []interface{}) error {
+  time.Sleep(ss.delay)
+  return nil
+}
+
+func TestSlowSink(t *testing.T) {
+  site := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    fmt.Fprint(w, `<p id="1"></p>`)
+  }))
+  defer site.Close()
+
+  // A sink that finishes after the timeout still records a success.
+  runner, err := NewRunner(Config{Jobs: []Job{{Name: "ids", Url: site.URL, Iterator: "p", Model: "id", Timeout: 500 * time.Millisecond, Sink: slowSink{time.Second}}}})
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if err = runner.RunJob(context.Background(), "ids"); err != nil {
+    t.Fatal(err)
+  }
+
+  if state := runner.State()["ids"]; state.Items != 1 || state.ConsecutiveFailures != 0 {
+    t.Fatalf("invalid state: %+v", state)
+  }
+}
@@ -0,0 +1,199 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+  "fmt"
+  "time"
+  "strconv"
+  "strings"
+)
+
+// Schedule determines when a job runs.
+type Schedule interface {
+  // Next returns the first time after t the job runs, or the zero time if it never runs again.
+  Next(t time.Time) time.Time
+}
+
+// descriptors are the shorthands for common cron expressions.
+var descriptors = map[string]string{
+  "@yearly": "0 0 1 1 *",
+  "@annually": "0 0 1 1 *",
+  "@monthly": "0 0 1 * *",
+  "@weekly": "0 0 * * 0",
+  "@daily": "0 0 * * *",
+  "@midnight": "0 0 * * *",
+  "@hourly": "0 * * * *",
+}
+
+// ParseSchedule parses a cron expression with the five fields minute, hour, day of month, month and day of
+// week (0 or 7 is Sunday). Each field is "*", a number, a range such as "1-5" or a comma separated list of
+// them, and any of them can have a step such as "*/15". As with cron, if both the day of month and the day
+// of week are restricted then a day matching either runs the job.
+//
+// The descriptors @yearly, @monthly, @weekly, @daily and @hourly are supported, as is "@every <duration>"
+// to run a job at a fixed interval, i.e. "@every 90s".
+//
+// Example:
+//
+//     schedule, err := jobs.ParseSchedule("*/15 9-17 * * 1-5")
+func ParseSchedule(spec string) (Schedule, error) {
+  spec = strings.TrimSpace(spec)
+
+  if strings.HasPrefix(spec, "@every ") {
+    d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+
+    if err != nil || d <= 0 {
+      return nil, fmt.Errorf("Invalid schedule %q: expected a positive duration", spec)
+    }
+
+    return every(d), nil
+  }
+
+  if expr,ok := descriptors[spec]; ok {
+    spec = expr
+  }
+
+  fields := strings.Fields(spec)
+
+  if len(fields) != 5 {
+    return nil, fmt.Errorf("Invalid schedule %q: expected 5 fields", spec)
+  }
+
+  bounds := [5][2]uint{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+  sets := [5]uint64{}
+
+  for i,field := range(fields) {
+    if set,err := parseField(field, bounds[i][0], bounds[i][1]); err == nil {
+      sets[i] = set
+    } else {
+      return nil, fmt.Errorf("Invalid schedule %q: %v", spec, err)
+    }
+  }
+
+  // Sunday can be written as 0 or 7.
+  if sets[4] & (1 << 7) != 0 {
+    sets[4] |= 1
+  }
+
+  return &cronSchedule{
+    minute: sets[0],
+    hour: sets[1],
+    dom: sets[2],
+    month: sets[3],
+    dow: sets[4],
+    anyDom: fields[2] == "*",
+    anyDow: fields[4] == "*",
+  }, nil
+}
+
+// parseField returns the set of values a cron field matches as a bit set.
+func parseField(field string, min uint, max uint) (uint64, error) {
+  var set uint64
+
+  for _,part := range(strings.Split(field, ",")) {
+    start, end, step := min, max, uint(1)
+    rng := part
+
+    if i := strings.Index(part, "/"); i >= 0 {
+      n, err := strconv.ParseUint(part[i+1:], 10, 8)
+
+      if err != nil || n == 0 {
+        return 0, fmt.Errorf("invalid step in %q", part)
+      }
+
+      rng, step = part[:i], uint(n)
+    }
+
+    if rng != "*" {
+      bounds := strings.SplitN(rng, "-", 2)
+      values := make([]uint, len(bounds))
+
+      for i,bound := range(bounds) {
+        n, err := strconv.ParseUint(bound, 10, 8)
+
+        if err != nil || uint(n) < min || uint(n) > max {
+          return 0, fmt.Errorf("%q is not a number from %d to %d", bound, min, max)
+        }
+
+        values[i] = uint(n)
+      }
+
+      start, end = values[0], values[len(values) - 1]
+
+      // A single value with a step runs from the value to the maximum, i.e. "5/15".
+      if len(values) == 1 && step > 1 {
+        end = max
+      }
+
+      if start > end {
+        return 0, fmt.Errorf("invalid range %q", rng)
+      }
+    }
+
+    for n := start; n <= end; n += step {
+      set |= 1 << n
+    }
+  }
+
+  return set, nil
+}
+
+// cronSchedule is a parsed cron expression. Each field is a bit set of the values it matches.
+type cronSchedule struct {
+  minute uint64
+  hour uint64
+  dom uint64
+  month uint64
+  dow uint64
+  anyDom bool
+  anyDow bool
+}
+
+func (cs *cronSchedule) Next(t time.Time) time.Time {
+  loc := t.Location()
+  t = t.Truncate(time.Minute).Add(time.Minute)
+  // Give up on expressions that never match, i.e. "0 0 30 2 *".
+  limit := t.Year() + 5
+
+  for t.Year() < limit {
+    if cs.month & (1 << uint(t.Month())) == 0 {
+      t = time.Date(t.Year(), t.Month() + 1, 1, 0, 0, 0, 0, loc)
+    } else if !cs.matchesDay(t) {
+      t = time.Date(t.Year(), t.Month(), t.Day() + 1, 0, 0, 0, 0, loc)
+    } else if cs.hour & (1 << uint(t.Hour())) == 0 {
+      t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour() + 1, 0, 0, 0, loc)
+    } else if cs.minute & (1 << uint(t.Minute())) == 0 {
+      t = t.Add(time.Minute)
+    } else {
+      return t
+    }
+  }
+
+  return time.Time{}
+}
+
+func (cs *cronSchedule) matchesDay(t time.Time) bool {
+  dom := cs.dom & (1 << uint(t.Day())) != 0
+  dow := cs.dow & (1 << uint(t.Weekday())) != 0
+
+  switch {
+    case cs.anyDom && cs.anyDow:
+      return true
+    case cs.anyDom:
+      return dow
+    case cs.anyDow:
+      return dom
+  }
+
+  return dom || dow
+}
+
+// every runs a job at a fixed interval.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+  return t.Add(time.Duration(e))
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+  "os"
+  "io"
+  "bytes"
+  "errors"
+  "github.com/dschnare/dtoo/export"
+  "github.com/dschnare/dtoo/internal/fsutil"
+  "github.com/dschnare/dtoo/sqlite"
+)
+
+// Sink stores the results of each run of a job.
+type Sink interface {
+  Write(results []interface{}) error
+}
+
+// FileSink writes the results of each run to a file in the json, ndjson, csv or tsv format. Ndjson files
+// are appended to so they keep the results of every run. Other files are replaced atomically by the
+// results of the latest run.
+type FileSink struct {
+  Path string
+  Format string
+  Options export.Options
+}
+
+func (fs FileSink) Write(results []interface{}) error {
+  if fs.Format == "ndjson" {
+    file, err := os.OpenFile(fs.Path, os.O_WRONLY | os.O_CREATE | os.O_APPEND, 0644)
+
+    if err != nil {
+      return err
+    }
+
+    if err = export.WriteAll(export.NewNDJSONWriter(file), results); err != nil {
+      file.Close()
+      return err
+    }
+
+    return file.Close()
+  }
+
+  var buf bytes.Buffer
+  w, err := newWriter(&buf, fs.Format, fs.Options)
+
+  if err == nil {
+    err = export.WriteAll(w, results)
+  }
+
+  if err != nil {
+    return err
+  }
+
+  return fsutil.WriteFileAtomic(fs.Path, buf.Bytes(), 0644)
+}
+
+func newWriter(w io.Writer, format string, opts export.Options) (export.Writer, error) {
+  switch format {
+    case "json":
+      return export.NewJSONWriter(w), nil
+    case "ndjson":
+      return export.NewNDJSONWriter(w), nil
+    case "csv":
+      return export.NewCSVWriter(w, opts), nil
+    case "tsv":
+      return export.NewTSVWriter(w, opts), nil
+  }
+
+  return nil, errors.New("Unsupported format " + format)
+}
+
+// SQLiteSink upserts the results of each run into a table of a SQLite database.
+type SQLiteSink struct {
+  Path string
+  Options sqlite.Options
+}
+
+func (ss SQLiteSink) Write(results []interface{}) error {
+  db, err := sqlite.Open(ss.Path)
+
+  if err != nil {
+    return err
+  }
+
+  defer db.Close()
+  return sqlite.NewSink(db, ss.Options).WriteAll(results)
+}
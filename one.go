@@ -0,0 +1,77 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "io"
+  "errors"
+  "github.com/PuerkitoBio/goquery"
+)
+
+// ErrNotFound is returned by the ScrapeOne functions when the iterator matches nothing.
+var ErrNotFound = errors.New("No match found")
+
+// ScrapeOneFromString scrapes the first match of the iterator in an HTML string according to the data model specified.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+//
+// Returns a value based on the data model specified, or ErrNotFound if the iterator matches nothing.
+//
+// Example:
+//
+//    dtoo.ScrapeOneFromString("article", dtoo.Model{id: 'id', content: 'text'}, html)
+func ScrapeOneFromString(iterator string, model interface{}, html string) (interface{}, error) {
+  return first(ScrapeFromStringWithLimit(iterator, model, html, 1))
+}
+
+// ScrapeOneFromReader scrapes the first match of the iterator in a file according to the data model specified.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+//
+// Returns a value based on the data model specified, or ErrNotFound if the iterator matches nothing.
+//
+// Example:
+//
+//    dtoo.ScrapeOneFromReader("article", dtoo.Model{id: 'id', content: 'text'}, reader)
+func ScrapeOneFromReader(iterator string, model interface{}, r io.Reader) (interface{}, error) {
+  return first(ScrapeFromReaderWithLimit(iterator, model, r, 1))
+}
+
+// ScrapeOneFromUrl scrapes the first match of the iterator in a URL according to the data model specified.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+//
+// Returns a value based on the data model specified, or ErrNotFound if the iterator matches nothing.
+//
+// Example:
+//
+//    dtoo.ScrapeOneFromUrl("article", dtoo.Model{id: 'id', content: 'text'}, url)
+func ScrapeOneFromUrl(iterator string, model interface{}, url string) (interface{}, error) {
+  return first(ScrapeFromUrlWithLimit(iterator, model, url, 1))
+}
+
+// ScrapeOne scrapes the first match of the iterator in a goquery.Selection object according to the data model specified.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+//
+// Returns a value based on the data model specified, or ErrNotFound if the iterator matches nothing.
+//
+// Example:
+//
+//    doc, err := goquery.NewDocument(url)
+//    if err == nil {
+//      dtoo.ScrapeOne("article", dtoo.Model{id: 'id', content: 'text'}, doc.Selection)
+//    }
+func ScrapeOne(iterator string, model interface{}, s *goquery.Selection) (interface{}, error) {
+  return first(Scrape(iterator, model, s, 1))
+}
+
+func first(results []interface{}, err error) (interface{}, error) {
+  if err != nil {
+    return nil, err
+  }
+
+  if len(results) == 0 {
+    return nil, ErrNotFound
+  }
+
+  return results[0], nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "testing"
+)
+
+func TestScrapeNoMatches(t *testing.T) {
+  if items,err := ScrapeFromString(".nothing", "text", "<p>text</p>"); err == nil {
+    if items == nil || len(items) != 0 {
+      t.Fatalf("invalid items: expected an empty slice got %v", items)
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestScrapeOne(t *testing.T) {
+  file, err := os.Open("./fixtures/index.html")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer file.Close()
+
+  if post,err := ScrapeOneFromReader(".post", Model{"Title": RetrieverModel{Sel: ".post-title", Method: "text"}}, file); err == nil {
+    if post.(Model)["Title"] != "Some Post 1  " {
+      t.Fatalf("invalid post: %v", post)
+    }
+  } else {
+    t.Fatal(err)
+  }
+
+  if item,err := ScrapeOneFromString(".nothing", "text", "<p>text</p>"); err != ErrNotFound || item != nil {
+    t.Fatalf("invalid result: expected %v got %v, %v", ErrNotFound, item, err)
+  }
+}
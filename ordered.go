@@ -0,0 +1,150 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "bytes"
+  "encoding/json"
+  "github.com/PuerkitoBio/goquery"
+)
+
+// Field is a single key of an OrderedModel.
+type Field struct {
+  Key string
+  // The data model to extract the key with, or the extracted value in a scrape result.
+  Value interface{}
+}
+
+/*
+OrderedModel is a complex model like Model except that its keys are evaluated in declaration order
+and the scraped result, also an OrderedModel, preserves that order when serialized. Use it when func
+retrievers have side effects or when output columns must be stable.
+
+Example:
+
+    dtoo.ScrapeFromUrl(".post", dtoo.OrderedModel{
+      {Key: "Id", Value: "id"},
+      {Key: "Title", Value: dtoo.RetrieverModel{Sel: ".post-title", Method: "text"}},
+    }, url)
+*/
+type OrderedModel []Field
+
+// Get returns the value of the specified key and whether the key exists.
+func (m OrderedModel) Get(key string) (interface{}, bool) {
+  for _,field := range(m) {
+    if field.Key == key {
+      return field.Value, true
+    }
+  }
+
+  return nil, false
+}
+
+// Keys returns the keys of the model in declaration order.
+func (m OrderedModel) Keys() []string {
+  keys := make([]string, len(m))
+
+  for i,field := range(m) {
+    keys[i] = field.Key
+  }
+
+  return keys
+}
+
+// Model converts the ordered model to an unordered Model.
+func (m OrderedModel) Model() Model {
+  model := Model{}
+
+  for _,field := range(m) {
+    model[field.Key] = field.Value
+  }
+
+  return model
+}
+
+// MarshalJSON serializes the model as a JSON object with its keys in declaration order.
+func (m OrderedModel) MarshalJSON() ([]byte, error) {
+  var buf bytes.Buffer
+  buf.WriteByte('{')
+
+  for i,field := range(m) {
+    if i > 0 {
+      buf.WriteByte(',')
+    }
+
+    if key,err := json.Marshal(field.Key); err == nil {
+      buf.Write(key)
+    } else {
+      return nil, err
+    }
+
+    buf.WriteByte(':')
+
+    if value,err := json.Marshal(field.Value); err == nil {
+      buf.Write(value)
+    } else {
+      return nil, err
+    }
+  }
+
+  buf.WriteByte('}')
+  return buf.Bytes(), nil
+}
+
+func (sc *scraper) extractOrderedModel(model OrderedModel, s *goquery.Selection, ctx *ScrapeContext) (OrderedModel, error) {
+  order, err := computedOrder(model.Keys(), model.Get)
+
+  if err != nil {
+    return nil, err
+  }
+
+  data := make(OrderedModel, len(model))
+  // The keys extracted so far, exposed to func retrievers as ScrapeContext.Parent and to Computed keys.
+  parent := Model{}
+  indexes := make([]int, 0, len(model))
+
+  for i,field := range(model) {
+    data[i].Key = field.Key
+
+    if !isComputed(field.Value) {
+      indexes = append(indexes, i)
+    }
+  }
+
+  extract := func (i int) (err error) {
+    data[i].Value, err = sc.extract(model[i].Value, s, ctx.field(model[i].Key, parent, model[i].Value))
+    return
+  }
+
+  if sc.opts.ParallelKeys {
+    if _,err := sc.each(len(indexes), func (i int) error { return extract(indexes[i]) }); err != nil {
+      return nil, err
+    }
+
+    for _,i := range(indexes) {
+      parent[data[i].Key] = data[i].Value
+    }
+  } else {
+    for _,i := range(indexes) {
+      if err := extract(i); err != nil {
+        return nil, err
+      }
+
+      parent[data[i].Key] = data[i].Value
+    }
+  }
+
+  if err := compute(order, model.Get, parent); err != nil {
+    return nil, err
+  }
+
+  for i,field := range(model) {
+    if isComputed(field.Value) {
+      data[i].Value = parent[field.Key]
+    }
+  }
+
+  return data, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "strings"
+  "testing"
+  "encoding/json"
+  "github.com/PuerkitoBio/goquery"
+)
+
+func TestScrapeOrderedModel(t *testing.T) {
+  file, err := os.Open("./fixtures/index.html")
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer file.Close()
+
+  evaluated := make([]string, 0)
+  track := func (key string, model interface{}) func (s *goquery.Selection) (interface{}, error) {
+    return func (s *goquery.Selection) (interface{}, error) {
+      evaluated = append(evaluated, key)
+      return (&scraper{}).extract(model, s, &ScrapeContext{})
+    }
+  }
+
+  posts, err := ScrapeFromReaderWithLimit(".post", OrderedModel{
+    {Key: "Title", Value: track("Title", RetrieverModel{Sel: ".post-title", Method: "text"})},
+    {Key: "Date", Value: track("Date", RetrieverModel{Sel: "time", Method: "text"})},
+    {Key: "Summary", Value: track("Summary", RetrieverModel{Sel: ".post-summary", Method: "text"})},
+  }, file, 1)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if strings.Join(evaluated, ",") != "Title,Date,Summary" {
+    t.Fatalf("invalid evaluation order: expected %v got %v", "Title,Date,Summary", evaluated)
+  }
+
+  post, ok := posts[0].(OrderedModel)
+
+  if !ok {
+    t.Fatalf("invalid post encountered: %v", posts[0])
+  }
+
+  if date,_ := post.Get("Date"); date != "August 26th, 2014" {
+    t.Fatalf("invalid date encountered: expected %v got %v", "August 26th, 2014", date)
+  }
+
+  expected := `{"Title":"Some Post 1  ","Date":"August 26th, 2014","Summary":"Some post 1 summary."}`
+
+  if data,err := json.Marshal(post); err != nil || string(data) != expected {
+    t.Fatalf("invalid JSON: expected %v got %v (%v)", expected, string(data), err)
+  }
+}
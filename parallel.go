@@ -0,0 +1,66 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "sync"
+  "sync/atomic"
+)
+
+// each calls fn for every index in [0, n) and returns the index and error of the first call that failed,
+// or n and nil if every call succeeded. Calls are made sequentially, stopping at the first error, unless
+// the scraper has a worker pool. Then calls are spread across the pool and run on the calling goroutine
+// whenever the pool is exhausted, so nested calls to each never deadlock. Once a call fails no further
+// calls are started.
+func (sc *scraper) each(n int, fn func (i int) error) (int, error) {
+  if sc.pool == nil || n < 2 {
+    for i := 0; i < n; i++ {
+      if err := fn(i); err != nil {
+        return i, err
+      }
+    }
+
+    return n, nil
+  }
+
+  var wg sync.WaitGroup
+  var failed int32
+  errs := make([]error, n)
+
+  call := func (i int) {
+    if errs[i] = fn(i); errs[i] != nil {
+      atomic.StoreInt32(&failed, 1)
+    }
+  }
+
+  for i := 0; i < n && atomic.LoadInt32(&failed) == 0; i++ {
+    select {
+      case sc.pool <- struct{}{}:
+        wg.Add(1)
+
+        go func (i int) {
+          defer func () {
+            <-sc.pool
+            wg.Done()
+          }()
+
+          call(i)
+        }(i)
+      default:
+        call(i)
+    }
+  }
+
+  wg.Wait()
+
+  // Calls that were never started come after the call that failed so the first error is always reported.
+  for i,err := range(errs) {
+    if err != nil {
+      return i, err
+    }
+  }
+
+  return n, nil
+}
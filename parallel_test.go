@@ -0,0 +1,107 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "fmt"
+  "sync"
+  "time"
+  "errors"
+  "strings"
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+func numberedList(n int) string {
+  items := make([]string, n)
+
+  for i := range(items) {
+    items[i] = fmt.Sprintf(`<li id="%v"><span>%v</span></li>`, i, i)
+  }
+
+  return "<ul>" + strings.Join(items, "") + "</ul>"
+}
+
+func TestScrapeWorkersPreserveOrder(t *testing.T) {
+  var mu sync.Mutex
+  active, maxActive := 0, 0
+
+  slowId := func (s *goquery.Selection) (interface{}, error) {
+    mu.Lock()
+    active++
+    if active > maxActive {
+      maxActive = active
+    }
+    mu.Unlock()
+
+    id, _ := s.Attr("id")
+    // Later items finish first.
+    time.Sleep(time.Duration(20 - len(id)) * time.Millisecond)
+
+    mu.Lock()
+    active--
+    mu.Unlock()
+
+    return id, nil
+  }
+
+  ids, err := ScrapeFromStringWithOptions("li", Model{
+    "Id": slowId,
+    "Spans": RetrieverModel{Scrape: ScrapeObject{Iterator: "span", Data: "text"}},
+  }, numberedList(20), ScrapeOptions{Workers: 4, ParallelKeys: true})
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if len(ids) != 20 {
+    t.Fatalf("item count invalid: expected %v got %v", 20, len(ids))
+  }
+
+  for i,data := range(ids) {
+    item := data.(Model)
+
+    if item["Id"] != fmt.Sprint(i) {
+      t.Fatalf("invalid item order: expected %v got %v", i, item["Id"])
+    }
+    if spans := item["Spans"].([]interface{}); spans[0] != fmt.Sprint(i) {
+      t.Fatalf("invalid nested item: expected %v got %v", i, spans[0])
+    }
+  }
+
+  if maxActive > 4 || maxActive < 2 {
+    t.Fatalf("invalid concurrency: expected between %v and %v got %v", 2, 4, maxActive)
+  }
+}
+
+func TestScrapeWorkersReportFirstError(t *testing.T) {
+  ids, err := ScrapeFromStringWithOptions("li", func (s *goquery.Selection) (interface{}, error) {
+    id, _ := s.Attr("id")
+
+    if id == "3" || id == "7" {
+      return nil, errors.New("bad item " + id)
+    }
+
+    return id, nil
+  }, numberedList(10), ScrapeOptions{Workers: 3})
+
+  if err == nil || err.Error() != "bad item 3" {
+    t.Fatalf("invalid error: expected %v got %v", "bad item 3", err)
+  }
+
+  if len(ids) != 3 {
+    t.Fatalf("item count invalid: expected %v got %v", 3, len(ids))
+  }
+}
+
+func TestScrapeWorkersWithLimit(t *testing.T) {
+  if ids,err := ScrapeFromStringWithOptions("li", "id", numberedList(10), ScrapeOptions{Workers: 3, Limit: 4}); err == nil {
+    if fmt.Sprint(ids) != "[0 1 2 3]" {
+      t.Fatalf("invalid items: expected %v got %v", "[0 1 2 3]", ids)
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
@@ -0,0 +1,217 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "bytes"
+  "errors"
+  "regexp"
+  "strings"
+  "io/ioutil"
+  "crypto/sha1"
+  "encoding/hex"
+  "encoding/json"
+  "path/filepath"
+  "net/http"
+  "github.com/dschnare/dtoo/internal/fsutil"
+)
+
+// ReplayMode specifies whether a ReplayTransport records or replays fixtures.
+type ReplayMode int
+
+const (
+  // Replay serves recorded responses and fails on requests that were not recorded.
+  Replay ReplayMode = iota
+  // Record makes real requests and saves them and their responses as fixtures.
+  Record
+)
+
+// UpdateEnv is the environment variable that asks tests to refresh their fixtures instead of checking
+// against them, e.g. "DTOO_UPDATE=1 go test ./...". Golden files of dtootest are rewritten and tests
+// should record ReplayTransport fixtures from the network.
+const UpdateEnv = "DTOO_UPDATE"
+
+// UpdateFixtures reports whether UpdateEnv is set to a non-empty value.
+func UpdateFixtures() bool {
+  return os.Getenv(UpdateEnv) != ""
+}
+
+/*
+ReplayTransport is an http.RoundTripper that records request/response pairs as fixtures and replays them
+so tests that scrape from URLs are deterministic and never touch the network.
+
+Each fixture is stored in Dir as two files: a .json file holding the request and the response status
+and headers, and a .body file holding the raw response body. Headers that carry credentials, such as
+Authorization, Cookie, Set-Cookie and API keys, are left out so fixtures can be committed.
+
+Example:
+
+    transport := &dtoo.ReplayTransport{Dir: "./fixtures/replay"}
+
+    if dtoo.UpdateFixtures() {
+      transport.Mode = dtoo.Record
+    }
+
+    client := &http.Client{Transport: transport}
+    dtoo.ScrapeFromUrlWithOptions(".post", "id", url, dtoo.ScrapeOptions{Client: client})
+*/
+type ReplayTransport struct {
+  // The directory fixtures are stored in. Created if it does not exist when recording.
+  Dir string
+  // Whether to record or replay fixtures. Defaults to Replay.
+  Mode ReplayMode
+  // The transport used to make requests when recording. Defaults to http.DefaultTransport.
+  Transport http.RoundTripper
+}
+
+// replayFixture is the serialized form of a recorded request/response pair. The body is stored separately.
+type replayFixture struct {
+  Request struct {
+    Method string
+    Url string
+    Header http.Header
+  }
+  Response struct {
+    Status int
+    Header http.Header
+  }
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+  path := filepath.Join(t.Dir, fixtureName(req))
+
+  if t.Mode == Record {
+    return t.record(path, req)
+  }
+
+  return t.replay(path, req)
+}
+
+func (t *ReplayTransport) record(path string, req *http.Request) (*http.Response, error) {
+  transport := t.Transport
+
+  if transport == nil {
+    transport = http.DefaultTransport
+  }
+
+  resp, err := transport.RoundTrip(req)
+
+  if err != nil {
+    return nil, err
+  }
+
+  defer resp.Body.Close()
+
+  body, err := ioutil.ReadAll(resp.Body)
+
+  if err != nil {
+    return nil, err
+  }
+
+  var fixture replayFixture
+  fixture.Request.Method = req.Method
+  fixture.Request.Url = req.URL.String()
+  fixture.Request.Header = withoutSecrets(req.Header)
+  fixture.Response.Status = resp.StatusCode
+  fixture.Response.Header = withoutSecrets(resp.Header)
+
+  if err = os.MkdirAll(t.Dir, 0755); err != nil {
+    return nil, err
+  }
+
+  data, err := json.MarshalIndent(fixture, "", "  ")
+
+  if err != nil {
+    return nil, err
+  }
+
+  // The .json file is written last since a fixture is only replayed once it exists.
+  if err = fsutil.WriteFileAtomic(path + ".body", body, 0644); err != nil {
+    return nil, err
+  }
+
+  if err = fsutil.WriteFileAtomic(path + ".json", data, 0644); err != nil {
+    return nil, err
+  }
+
+  resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+  return resp, nil
+}
+
+func (t *ReplayTransport) replay(path string, req *http.Request) (*http.Response, error) {
+  var fixture replayFixture
+
+  data, err := ioutil.ReadFile(path + ".json")
+
+  if os.IsNotExist(err) {
+    return nil, errors.New("No recorded response for " + req.Method + " " + req.URL.String())
+  } else if err != nil {
+    return nil, err
+  }
+
+  if err = json.Unmarshal(data, &fixture); err != nil {
+    return nil, err
+  }
+
+  body, err := ioutil.ReadFile(path + ".body")
+
+  if err != nil {
+    return nil, err
+  }
+
+  return &http.Response{
+    Status: http.StatusText(fixture.Response.Status),
+    StatusCode: fixture.Response.Status,
+    Proto: "HTTP/1.1",
+    ProtoMajor: 1,
+    ProtoMinor: 1,
+    Header: fixture.Response.Header,
+    Body: ioutil.NopCloser(bytes.NewReader(body)),
+    ContentLength: int64(len(body)),
+    Request: req,
+  }, nil
+}
+
+// secretHeaderWords are the words of header names that carry credentials, i.e. Authorization, Cookie,
+// Set-Cookie and X-Api-Key. Such headers are never saved in fixtures since fixtures get committed.
+var secretHeaderWords = []string{"auth", "cookie", "token", "secret", "key", "session", "password"}
+
+// withoutSecrets returns a copy of h without the headers that carry credentials.
+func withoutSecrets(h http.Header) http.Header {
+  clean := make(http.Header, len(h))
+
+  for name,values := range(h) {
+    secret := false
+
+    for _,word := range(secretHeaderWords) {
+      if strings.Contains(strings.ToLower(name), word) {
+        secret = true
+        break
+      }
+    }
+
+    if !secret {
+      clean[name] = values
+    }
+  }
+
+  return clean
+}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixtureName returns a readable file name that is unique to the method and URL of req.
+func fixtureName(req *http.Request) string {
+  sum := sha1.Sum([]byte(req.Method + " " + req.URL.String()))
+  name := unsafeFixtureChars.ReplaceAllString(req.URL.Host + req.URL.Path, "_")
+
+  if len(name) > 80 {
+    name = name[:80]
+  }
+
+  return req.Method + "_" + strings.Trim(name, "_") + "_" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,110 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "os"
+  "io"
+  "strings"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+  "net/http"
+  "net/http/httptest"
+)
+
+func TestReplayTransportRecordAndReplay(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-replay")
+  defer os.RemoveAll(dir)
+
+  server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "text/html; charset=windows-1252")
+    w.WriteHeader(http.StatusAccepted)
+    io.WriteString(w, "<ul><li>Caf\xe9</li></ul>")
+  }))
+  url := server.URL + "/menu?page=1"
+
+  recorder := &http.Client{Transport: &ReplayTransport{Dir: dir, Mode: Record}}
+
+  if items,err := ScrapeFromUrlWithOptions("li", "text", url, ScrapeOptions{Client: recorder}); err != nil || items[0] != "Café" {
+    t.Fatalf("invalid recorded item: expected %v got %v (%v)", "Café", items, err)
+  }
+
+  // Replaying must not touch the network.
+  server.Close()
+  replayer := &http.Client{Transport: &ReplayTransport{Dir: dir}}
+
+  if resp,err := replayer.Get(url); err == nil {
+    if resp.StatusCode != http.StatusAccepted {
+      t.Fatalf("invalid status replayed: expected %v got %v", http.StatusAccepted, resp.StatusCode)
+    }
+    resp.Body.Close()
+  } else {
+    t.Fatal(err)
+  }
+
+  if items,err := ScrapeFromUrlWithOptions("li", "text", url, ScrapeOptions{Client: replayer}); err != nil || items[0] != "Café" {
+    t.Fatalf("invalid replayed item: expected %v got %v (%v)", "Café", items, err)
+  }
+
+  if _,err := ScrapeFromUrlWithOptions("li", "text", server.URL + "/menu?page=2", ScrapeOptions{Client: replayer}); err == nil {
+    t.Fatalf("expected an error for an unrecorded request")
+  }
+}
+
+func TestReplayTransportOmitsSecrets(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "dtoo-replay")
+  defer os.RemoveAll(dir)
+
+  server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    http.SetCookie(w, &http.Cookie{Name: "session", Value: "response-secret"})
+    io.WriteString(w, "<p>ok</p>")
+  }))
+  defer server.Close()
+
+  req, _ := http.NewRequest("GET", server.URL, nil)
+  req.Header.Set("Authorization", "Bearer request-secret")
+  req.Header.Set("Cookie", "session=request-secret")
+  req.Header.Set("X-Api-Key", "request-secret")
+  req.Header.Set("Accept-Language", "en")
+
+  if resp,err := (&http.Client{Transport: &ReplayTransport{Dir: dir, Mode: Record}}).Do(req); err == nil {
+    resp.Body.Close()
+  } else {
+    t.Fatal(err)
+  }
+
+  files, _ := ioutil.ReadDir(dir)
+
+  if len(files) != 2 {
+    t.Fatalf("expected a .json and a .body file got %v files", len(files))
+  }
+
+  for _,file := range(files) {
+    data, _ := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+
+    if strings.Contains(string(data), "secret") {
+      t.Fatalf("secret saved in %v: %s", file.Name(), data)
+    }
+
+    if strings.HasSuffix(file.Name(), ".json") && !strings.Contains(string(data), "Accept-Language") {
+      t.Fatalf("expected other headers to be saved: %s", data)
+    }
+  }
+}
+
+func TestUpdateFixtures(t *testing.T) {
+  t.Setenv(UpdateEnv, "")
+
+  if UpdateFixtures() {
+    t.Fatal("expected no update without " + UpdateEnv)
+  }
+
+  t.Setenv(UpdateEnv, "1")
+
+  if !UpdateFixtures() {
+    t.Fatal("expected an update with " + UpdateEnv + " set")
+  }
+}
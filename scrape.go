@@ -5,10 +5,16 @@
 package dtoo
 
 import (
-  "bytes"
   "io"
+  "fmt"
+  "context"
   "html"
   "errors"
+  "sort"
+  "reflect"
+  "strings"
+  "net/url"
+  "net/http"
   "github.com/PuerkitoBio/goquery"
 )
 
@@ -17,7 +23,7 @@ const (
   EMPTYSTRING = ""
 )
 
-// Model is a complex model that is a map of string:interface{}.
+// Model is a complex model that is a map of string:interface{}. Keys are evaluated in sorted order.
 type Model map[string]interface{}
 
 /*
@@ -34,6 +40,10 @@ Retrieves a slice of post published dates via the datetime attribute.
 
     dtoo.ScrapeFromUrl(".post", dtoo.RetrieverModel{Sel: ".publisehed-date", Attr: "datetime"}, url) 
 
+Retrieves a slice of absolute post permalinks resolved against the document base.
+
+    dtoo.ScrapeFromUrl(".post", dtoo.RetrieverModel{Sel: ".permalink", Attr: "href", Method: "absUrl"}, url)
+
 Retrieve a slice of slice of comment authors.
    
     dtoo.ScrapeFromUrl(".post", dtoo.RetrieverModel{Scrape: dtoo.ScrapeObject{Iterator: ".comment-author", Data: "text"}}, url)
@@ -43,12 +53,17 @@ type RetrieverModel struct {
   Attr string
   // The CSS selector to extract from.
   Sel string
-  // The method to use for the extraction. Can be set to "text", "html" or a func(*goquery.Selection)(interface{}, error). Required if Sel is set.
+  // The method to use for the extraction. Can be set to "text", "html", "absUrl", a func(*goquery.Selection)(interface{}, error)
+  // or a func(*goquery.Selection, *dtoo.ScrapeContext)(interface{}, error). Required if Sel is set.
+  // The "absUrl" method resolves the value of Attr (or the href or src attribute if Attr is not set) against the document base.
   Method interface{}
   // If set to a dtoo.ScrapeObject then a recursive scrape will be executed.
   Scrape ScrapeObject
-  // If set and the retrieved value is nil or the empty string then returns this value.
+  // If set then returns this value when Sel matches nothing or the retrieved value is empty, that is nil,
+  // the empty string or an empty slice or map. Applies to every kind of retrieval, including recursive scrapes.
   DefaultValue interface{}
+  // If true and DefaultValue is set then also returns DefaultValue instead of an error when the retrieval fails.
+  DefaultOnError bool
 }
 
 // ScrapeObject is an object that specifies settings for recursive scraping.
@@ -57,11 +72,141 @@ type ScrapeObject struct {
   Iterator string
   // The data model for the recursive scrape. Can be any data model type accepted by the Scrape functions
   Data interface{}
+  // Controls which matches of Iterator are extracted. Only the iteration settings (Offset, Limit, Step,
+  // Reverse, Filter and Not) apply to recursive scrapes.
+  Options ScrapeOptions
+}
+
+// ScrapeOptions specifies settings that control how a document is loaded and scraped.
+//
+// The iteration settings are applied to the matches of the root iterator before extraction in this
+// order: matches are filtered by Filter and Not, reversed if Reverse is set, the first Offset matches
+// are skipped, every Step-th match is kept and finally the result is truncated to Limit matches.
+type ScrapeOptions struct {
+  // The number of matches to skip.
+  Offset uint
+  // The maximum number of results. Skipped items do not count towards it. If 0 then no limit is applied.
+  Limit uint
+  // Extract every Step-th match, starting with the first one after Offset. If 0 or 1 then every match is extracted.
+  Step uint
+  // If true then matches are iterated in reverse document order.
+  Reverse bool
+  // If set then only matches for which Filter returns true are extracted.
+  Filter func (s *goquery.Selection) bool
+  // If set then matches that match this CSS selector are not extracted, like the :not() pseudo-class.
+  Not string
+  // The character encoding of the document (i.e. "shift_jis", "windows-1252" or "iso-8859-1").
+  // If empty then the encoding is detected from the BOM, the Content-Type header and any
+  // <meta charset> tag, and the document is transcoded to UTF-8 before it is parsed.
+  Charset string
+  // The URL that relative URLs are resolved against. Defaults to the URL of the fetched document
+  // when scraping from a URL. A <base href> tag in the document is resolved against this URL and
+  // takes precedence.
+  BaseUrl string
+  // If true then URL-valued attributes such as href and src are resolved to absolute URLs
+  // against the document base. Use the "absUrl" RetrieverModel method to resolve individual values.
+  ResolveUrls bool
+  // The HTTP client used to fetch documents when scraping from a URL. Defaults to http.DefaultClient.
+  // Set its Transport to a CacheTransport to cache responses on disk.
+  Client *http.Client
+  // The maximum number of goroutines used to extract iterations (including those of nested scrapes)
+  // in parallel. Results keep the order of the iterations. If 0 or 1 then extraction is sequential.
+  // Func retrievers and "method" funcs must be safe for concurrent use when this is greater than 1.
+  Workers int
+  // If true and Workers is greater than 1 then the keys of Model and OrderedModel data models are
+  // also evaluated in parallel, so they are no longer evaluated in a deterministic order.
+  ParallelKeys bool
+  // User-supplied values made available to func retrievers through ScrapeContext.Values.
+  Values map[string]interface{}
+  // If set then called with the index of the FirstOf candidate that yielded a value, or -1 if none did.
+  // Must be safe for concurrent use when Workers is greater than 1. See CandidateStats.
+  OnCandidate func (ctx *ScrapeContext, candidate int)
+}
+
+// ScrapeFromStringWithOptions scrapes content from an HTML string according to the data model and options specified.
+// Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+// Go strings are expected to be UTF-8 so no encoding detection is performed unless opts.Charset is set.
+//
+// Returns a value based on the data model specified. See package examples for more info.
+//
+// Example:
+//
+//    dtoo.ScrapeFromStringWithOptions("li", dtoo.Model{id: 'id', content: 'text'}, html, dtoo.ScrapeOptions{Limit: 10})
+func ScrapeFromStringWithOptions(iterator string, model interface{}, html string, opts ScrapeOptions) ([]interface{}, error) {
+  return ScrapeFromStringWithContext(context.Background(), iterator, model, html, opts)
+}
+
+// ScrapeFromStringWithContext is like ScrapeFromStringWithOptions but stops extracting with the error of ctx
+// once ctx is done. Func retrievers can check ScrapeContext.Context to stop early too.
+func ScrapeFromStringWithContext(ctx context.Context, iterator string, model interface{}, html string, opts ScrapeOptions) ([]interface{}, error) {
+  if opts.Charset == EMPTYSTRING {
+    opts.Charset = "utf-8"
+  }
+
+  return ScrapeFromReaderWithContext(ctx, iterator, model, strings.NewReader(html), opts)
+}
+
+// ScrapeFromReaderWithOptions scrapes content from a file according to the data model and options specified.
+// Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+//
+// Returns a value based on the data model specified. See package examples for more info.
+//
+// Example:
+//
+//    dtoo.ScrapeFromReaderWithOptions("li", dtoo.Model{id: 'id', content: 'text'}, reader, dtoo.ScrapeOptions{Charset: "shift_jis"})
+func ScrapeFromReaderWithOptions(iterator string, model interface{}, r io.Reader, opts ScrapeOptions) ([]interface{}, error) {
+  return ScrapeFromReaderWithContext(context.Background(), iterator, model, r, opts)
+}
+
+// ScrapeFromReaderWithContext is like ScrapeFromReaderWithOptions but stops extracting with the error of ctx
+// once ctx is done. Func retrievers can check ScrapeContext.Context to stop early too.
+func ScrapeFromReaderWithContext(ctx context.Context, iterator string, model interface{}, r io.Reader, opts ScrapeOptions) ([]interface{}, error) {
+  doc, err := newDocument(r, EMPTYSTRING, opts.Charset)
+
+  if err == nil {
+    return scrapeDocument(ctx, iterator, model, doc, opts)
+  } else {
+    return nil, err
+  }
+}
+
+// ScrapeFromUrlWithOptions scrapes content from a URL according to the data model and options specified.
+// Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+//
+// Returns a value based on the data model specified. See package examples for more info.
+//
+// Example:
+//
+//    dtoo.ScrapeFromUrlWithOptions("li", dtoo.Model{id: 'id', content: 'text'}, url, dtoo.ScrapeOptions{Limit: 10})
+func ScrapeFromUrlWithOptions(iterator string, model interface{}, url string, opts ScrapeOptions) ([]interface{}, error) {
+  return ScrapeFromUrlWithContext(context.Background(), iterator, model, url, opts)
+}
+
+// ScrapeFromUrlWithContext is like ScrapeFromUrlWithOptions but fetches the URL and extracts with a context
+// so the scrape can be cancelled or given a deadline. Func retrievers can check ScrapeContext.Context to
+// stop early too.
+//
+// Example:
+//
+//    ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+//    defer cancel()
+//    dtoo.ScrapeFromUrlWithContext(ctx, "li", dtoo.Model{id: 'id', content: 'text'}, url, dtoo.ScrapeOptions{})
+func ScrapeFromUrlWithContext(ctx context.Context, iterator string, model interface{}, url string, opts ScrapeOptions) ([]interface{}, error) {
+  doc, err := fetchDocument(ctx, url, opts)
+
+  if err == nil {
+    return scrapeDocument(ctx, iterator, model, doc, opts)
+  } else {
+    return nil, err
+  }
 }
 
 // ScrapeFromStringWithLimit scrapes content from an HTML string according to the data model specified up to a limit.
 // Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
-// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model or RetrieverModel.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
 // Will iterate up to limit number of iterations. If limit is 0 then no limit is applied.
 //
 // Returns a value based on the data model specified. See package examples for more info.
@@ -70,12 +215,12 @@ type ScrapeObject struct {
 //
 //    dtoo.ScrapeFromStringWithLimit("li", dtoo.Model{id: 'id', content: 'text'}, html, 0)
 func ScrapeFromStringWithLimit(iterator string, model interface{}, html string, limit uint) ([]interface{}, error) {
-  return ScrapeFromReaderWithLimit(iterator, model, bytes.NewBufferString(html), limit)
+  return ScrapeFromStringWithOptions(iterator, model, html, ScrapeOptions{Limit: limit})
 }
 
 // ScrapeFromReaderWithLimit scrapes content from a file according to the data model specified up to a limit.
 // Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
-// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model or RetrieverModel.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
 // Will iterate up to limit number of iterations. If limit is 0 then no limit is applied.
 //
 // Returns a value based on the data model specified. See package examples for more info.
@@ -84,18 +229,12 @@ func ScrapeFromStringWithLimit(iterator string, model interface{}, html string,
 // 
 //    dtoo.ScrapeFromReaderWithLimit("li", dtoo.Model{id: 'id', content: 'text'}, reader, 0)
 func ScrapeFromReaderWithLimit(iterator string, model interface{}, r io.Reader, limit uint) ([]interface{}, error) {
-  doc, err := goquery.NewDocumentFromReader(r)
-
-  if err == nil {
-    return Scrape(iterator, model, doc.Selection, limit)
-  } else {
-    return nil, err
-  }
+  return ScrapeFromReaderWithOptions(iterator, model, r, ScrapeOptions{Limit: limit})
 }
 
 // ScrapeFromUrlWithLimit scrapes content from a URL according to the data model specified up to a limit.
 // Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
-// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model or RetrieverModel.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
 // Will iterate up to limit number of iterations. If limit is 0 then no limit is applied.
 //
 // Returns a value based on the data model specified. See package examples for more info.
@@ -104,18 +243,12 @@ func ScrapeFromReaderWithLimit(iterator string, model interface{}, r io.Reader,
 //
 //    dtoo.ScrapeFromUrlWithLimit("li", dtoo.Model{id: 'id', content: 'text'}, url, 0)
 func ScrapeFromUrlWithLimit(iterator string, model interface{}, url string, limit uint) ([]interface{}, error) {
-  doc, err := goquery.NewDocument(url)
-
-  if err == nil {
-    return Scrape(iterator, model, doc.Selection, limit)
-  } else {
-    return nil, err
-  }
+  return ScrapeFromUrlWithOptions(iterator, model, url, ScrapeOptions{Limit: limit})
 }
 
 // ScrapeFromString scrapes content from an HTML string according to the data model specified.
 // Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
-// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model or RetrieverModel.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
 //
 // Returns a value based on the data model specified. See package examples for more info.
 //
@@ -123,12 +256,12 @@ func ScrapeFromUrlWithLimit(iterator string, model interface{}, url string, limi
 //
 //    dtoo.ScrapeFromString("li", dtoo.Model{id: 'id', content: 'text'}, html)
 func ScrapeFromString(iterator string, model interface{}, html string) ([]interface{}, error) {
-  return ScrapeFromReaderWithLimit(iterator, model, bytes.NewBufferString(html), 0)
+  return ScrapeFromStringWithOptions(iterator, model, html, ScrapeOptions{})
 }
 
 // ScrapeFromReader scrapes content from a file according to the data model specified.
 // Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
-// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model or RetrieverModel.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
 //
 // Returns a value based on the data model specified. See package examples for more info.
 //
@@ -141,7 +274,7 @@ func ScrapeFromReader(iterator string, model interface{}, r io.Reader) ([]interf
 
 // ScrapeFromUrl scrapes content from a URL according to the data model specified.
 // Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
-// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model or RetrieverModel.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
 //
 // Returns a value based on the data model specified. See package examples for more info.
 //
@@ -155,9 +288,10 @@ func ScrapeFromUrl(iterator string, model interface{}, url string) ([]interface{
 // Scrape scrapes content from a goquery.Selection object according to the data model specified.
 // Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
 // Will iterate up to limit number of iterations. If limit is 0 then no limit is applied.
-// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model or RetrieverModel.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
 //
-// Returns a value based on the data model specified. See package examples for more info.
+// Returns a value based on the data model specified, or an empty slice if the iterator matches nothing.
+// See package examples for more info.
 //
 // Example:
 //
@@ -166,48 +300,197 @@ func ScrapeFromUrl(iterator string, model interface{}, url string) ([]interface{
 //      Scrape("li", dtoo.Model{id: 'id', content: 'text'}, doc.Selection, 0)
 //    }
 func Scrape(iterator string, model interface{}, s *goquery.Selection, limit uint) ([]interface{}, error) {
-  var theError error = nil
-  c := uint(0)
-  result := make([]interface{}, 1)
+  return ScrapeWithOptions(iterator, model, s, ScrapeOptions{Limit: limit})
+}
 
-  s.Find(iterator).EachWithBreak(func (i int, s *goquery.Selection) bool {
-    data, err := extract(model, s)
+// ScrapeWithOptions scrapes content from a goquery.Selection object according to the data model and options specified.
+// Takes a selector as its root iterator and then takes the data model you intend to extract at each iteration.
+// The data model can be a string, (s *goquery.Selection) (interface{}, error), Model, OrderedModel or RetrieverModel.
+//
+// Returns a value based on the data model specified. See package examples for more info.
+//
+// Example:
+//
+//    doc, err := goquery.NewDocument(url)
+//    if err == nil {
+//      ScrapeWithOptions("li", dtoo.Model{id: 'id', content: 'text'}, doc.Selection, dtoo.ScrapeOptions{Workers: 4})
+//    }
+func ScrapeWithOptions(iterator string, model interface{}, s *goquery.Selection, opts ScrapeOptions) ([]interface{}, error) {
+  if sc,err := newScraper(s, nil, opts); err == nil {
+    return sc.scrape(iterator, model, s, opts, sc.rootContext())
+  } else {
+    return nil, err
+  }
+}
 
-    if err == nil {
-      if c >= uint(len(result)) {
-        result = append(result, data)
-      } else {
-        result[c] = data
+// scraper holds the state shared by every extraction performed during a single scrape.
+type scraper struct {
+  opts ScrapeOptions
+  // The document being scraped.
+  doc *scrapedDocument
+  // Holds a token for every goroutine extracting in parallel. Nil if extraction is sequential.
+  pool chan struct{}
+  // The compiled model being extracted, if any. Its compiled selectors are used by find.
+  compiled *CompiledModel
+  // The URL of the document being scraped. Can be nil.
+  docUrl *url.URL
+  // Extraction stops once it is done.
+  ctx context.Context
+}
+
+func newScraper(s *goquery.Selection, docUrl *url.URL, opts ScrapeOptions) (*scraper, error) {
+  if doc,err := newScrapedDocument(s, docUrl, opts.BaseUrl); err == nil {
+    sc := &scraper{opts: opts, doc: doc, docUrl: docUrl, ctx: context.Background()}
+
+    // The calling goroutine also extracts so it counts as a worker.
+    if opts.Workers > 1 {
+      sc.pool = make(chan struct{}, opts.Workers - 1)
+    }
+
+    return sc, nil
+  } else {
+    return nil, err
+  }
+}
+
+func scrapeDocument(ctx context.Context, iterator string, model interface{}, doc *goquery.Document, opts ScrapeOptions) ([]interface{}, error) {
+  if sc,err := newScraper(doc.Selection, doc.Url, opts); err == nil {
+    sc.ctx = ctx
+    return sc.scrape(iterator, model, doc.Selection, opts, sc.rootContext())
+  } else {
+    return nil, err
+  }
+}
+
+// scrape extracts the data model from the matches of iterator that are selected by the iteration settings of opts.
+func (sc *scraper) scrape(iterator string, model interface{}, s *goquery.Selection, opts ScrapeOptions, ctx *ScrapeContext) ([]interface{}, error) {
+  if cm,ok := model.(*CompiledModel); ok && sc.compiled != cm {
+    compiled := *sc
+    compiled.compiled = cm
+    return compiled.scrape(iterator, cm.model, s, opts, ctx)
+  }
+
+  // Skipped items don't count towards the limit so it is applied while extracting.
+  limit := int(opts.Limit)
+  opts.Limit = 0
+  items := sc.iterate(sc.find(s, iterator), opts)
+  results := make([]interface{}, 0)
+
+  for start := 0; start < len(items) && (limit == 0 || len(results) < limit); {
+    // Extract just enough items to reach the limit unless some of them are skipped.
+    end := len(items)
+
+    if limit > 0 && start + limit - len(results) < end {
+      end = start + limit - len(results)
+    }
+
+    values := make([]interface{}, end - start)
+    skipped := make([]bool, end - start)
+    c, err := sc.each(end - start, func (i int) (err error) {
+      if err = sc.ctx.Err(); err != nil {
+        return
+      }
+
+      if values[i], err = sc.extract(model, items[start + i], ctx.item(start + i, model)); errors.Is(err, ErrSkip) {
+        skipped[i], err = true, nil
+      }
+      return
+    })
+
+    // Skipped items are dropped from the results.
+    for i := 0; i < c; i++ {
+      if !skipped[i] {
+        results = append(results, values[i])
       }
-    } else {
-      theError = err
     }
 
-    c++
-    
-    // If we return false then the loop will be broken.
-    return !(c == limit || theError != nil)
-  })
+    if err != nil {
+      return results, err
+    }
+
+    start = end
+  }
 
-  return result, theError
+  return results, nil
 }
 
-func extract(model interface{}, s *goquery.Selection) (interface{}, error) {
+// StatusError is returned when a URL is fetched with a response status other than 2xx, so error pages
+// are not scraped as if they were the page expected.
+type StatusError struct {
+  // The URL of the response, after redirects.
+  Url string
+  StatusCode int
+}
+
+func (e *StatusError) Error() string {
+  return fmt.Sprintf("%v: unexpected status %v %v", e.Url, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func fetchDocument(ctx context.Context, rawurl string, opts ScrapeOptions) (*goquery.Document, error) {
+  client := opts.Client
+
+  if client == nil {
+    client = http.DefaultClient
+  }
+
+  req, err := http.NewRequestWithContext(ctx, "GET", rawurl, nil)
+
+  if err != nil {
+    return nil, err
+  }
+
+  resp, err := client.Do(req)
+
+  if err != nil {
+    return nil, err
+  }
+
+  defer resp.Body.Close()
+
+  if resp.StatusCode < 200 || resp.StatusCode > 299 {
+    return nil, &StatusError{Url: resp.Request.URL.String(), StatusCode: resp.StatusCode}
+  }
+
+  if doc,err := newDocument(resp.Body, resp.Header.Get("Content-Type"), opts.Charset); err == nil {
+    doc.Url = resp.Request.URL
+    return doc, nil
+  } else {
+    return nil, err
+  }
+}
+
+func (sc *scraper) extract(model interface{}, s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
   switch modelValue := model.(type) {
     case string:
-      return extractString(modelValue, s)
+      return sc.extractString(modelValue, s)
     case RetrieverModel:
-      return extractRetrieverModel(modelValue, s)
+      return sc.extractRetrieverModel(modelValue, s, ctx)
     case Model:
-      return extractDataModel(modelValue, s)
+      return sc.extractDataModel(modelValue, s, ctx)
+    case OrderedModel:
+      return sc.extractOrderedModel(modelValue, s, ctx)
+    case *CompiledModel:
+      compiled := *sc
+      compiled.compiled = modelValue
+      return compiled.extract(modelValue.model, s, ctx)
     case func (s *goquery.Selection) (interface{}, error):
       return modelValue(s)
+    case func (s *goquery.Selection, ctx *ScrapeContext) (interface{}, error):
+      return modelValue(s, ctx)
+    case FirstOf:
+      return sc.extractFirstOf(modelValue, s, ctx)
+    case Switch:
+      return sc.extractSwitch(modelValue, s, ctx)
+    case skipModel:
+      return nil, ErrSkip
+    case Computed:
+      return nil, errors.New("Computed can only be used as a Model or OrderedModel value")
     default:
       return nil, errors.New("Unsupported retriever type")
   }
 }
 
-func extractString(model string, s *goquery.Selection) (string, error) {
+func (sc *scraper) extractString(model string, s *goquery.Selection) (string, error) {
   switch model {
     // the text of the selection
     case "text":
@@ -217,23 +500,51 @@ func extractString(model string, s *goquery.Selection) (string, error) {
     // attribute name
     default:
       if attrValue,hasAttr := s.Attr(model); hasAttr {
-        return attrValue, nil
+        return sc.resolveAttr(model, attrValue), nil
       } else {
         return EMPTYSTRING, nil
       }
   }
 }
 
-func extractRetrieverModel(rm RetrieverModel, s *goquery.Selection) (interface{}, error) {
+func (sc *scraper) extractRetrieverModel(rm RetrieverModel, s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
   if rm.Sel != EMPTYSTRING {
-    s = s.Find(rm.Sel)
+    s = sc.find(s, rm.Sel)
+
+    // A selector that matches nothing has nothing to extract.
+    if s.Length() == 0 && rm.DefaultValue != nil {
+      return rm.DefaultValue, nil
+    }
+  }
+
+  value, err := sc.retrieve(rm, s, ctx)
+
+  if err != nil {
+    if rm.DefaultOnError && rm.DefaultValue != nil && !errors.Is(err, ErrSkip) {
+      return rm.DefaultValue, nil
+    }
+
+    return nil, err
+  }
+
+  if rm.DefaultValue != nil && IsEmpty(value) {
+    return rm.DefaultValue, nil
   }
 
+  return value, nil
+}
+
+// retrieve extracts the value of a RetrieverModel from the selection its Sel has already been applied to.
+func (sc *scraper) retrieve(rm RetrieverModel, s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
   if rm.Attr != EMPTYSTRING {
     if attrValue,hasAttr := s.Attr(rm.Attr); hasAttr {
-      return attrValue, nil
+      if rm.Method == "absUrl" {
+        return resolveUrl(sc.doc.base(), attrValue), nil
+      }
+
+      return sc.resolveAttr(rm.Attr, attrValue), nil
     } else {
-      return rm.DefaultValue, nil
+      return nil, nil
     }
   } else if rm.Method != nil {
     switch method := rm.Method.(type) {
@@ -243,27 +554,137 @@ func extractRetrieverModel(rm RetrieverModel, s *goquery.Selection) (interface{}
             return html.UnescapeString(s.Text()), nil
           case "html":
             return s.Html()
+          case "absUrl":
+            for _,attr := range([]string{"href", "src"}) {
+              if attrValue,hasAttr := s.Attr(attr); hasAttr {
+                return resolveUrl(sc.doc.base(), attrValue), nil
+              }
+            }
+
+            return nil, nil
         }
       case func (s *goquery.Selection) (interface{}, error):
         return method(s)
+      case func (s *goquery.Selection, ctx *ScrapeContext) (interface{}, error):
+        return method(s, ctx)
       default:
         return nil, errors.New("RetrieverModel: unrecognized 'method' type " + rm.Sel)
     }
   } else if rm.Scrape.Iterator != EMPTYSTRING && rm.Scrape.Data != EMPTYSTRING {
-    return Scrape(rm.Scrape.Iterator, rm.Scrape.Data, s, 0)
+    return sc.scrape(rm.Scrape.Iterator, rm.Scrape.Data, s, rm.Scrape.Options, ctx)
   }
 
   return nil, errors.New("Empty RetrieverModel encountered")
 }
 
-func extractDataModel(model Model, s *goquery.Selection) (data Model, err error) {
+// IsEmpty reports whether value is nil, the empty string or an empty slice or map. These are the values
+// replaced by RetrieverModel.DefaultValue and skipped by FirstOf.
+func IsEmpty(value interface{}) bool {
+  if value == nil {
+    return true
+  }
+
+  switch v := reflect.ValueOf(value); v.Kind() {
+    case reflect.String, reflect.Slice, reflect.Map:
+      return v.Len() == 0
+    case reflect.Ptr, reflect.Interface:
+      return v.IsNil()
+  }
+
+  return false
+}
+
+func (sc *scraper) extractDataModel(model Model, s *goquery.Selection, ctx *ScrapeContext) (data Model, err error) {
   data = Model{}
 
-  for key,retriever := range(model) {
-    if data[key],err = extract(retriever, s); err != nil {
-      break
+  lookup := func (key string) (value interface{}, exists bool) {
+    value, exists = model[key]
+    return
+  }
+  plan, err := sc.plan(model)
+
+  if err != nil {
+    return nil, err
+  }
+
+  keys, order := plan.keys, plan.order
+
+  if sc.opts.ParallelKeys {
+    values := make([]interface{}, len(keys))
+
+    if _,err = sc.each(len(keys), func (i int) (err error) {
+      values[i], err = sc.extract(model[keys[i]], s, ctx.field(keys[i], data, model[keys[i]]))
+      return
+    }); err != nil {
+      return
+    }
+
+    for i,key := range(keys) {
+      data[key] = values[i]
+    }
+  } else {
+    for _,key := range(keys) {
+      if data[key],err = sc.extract(model[key], s, ctx.field(key, data, model[key])); err != nil {
+        return
+      }
     }
   }
 
+  err = compute(order, lookup, data)
   return
-}
\ No newline at end of file
+}
+
+// modelPlan is the order the keys of a Model are extracted in.
+type modelPlan struct {
+  // The keys that are not Computed, in sorted order.
+  keys []string
+  // The Computed keys, in dependency order.
+  order []string
+}
+
+// newModelPlan orders the keys of model. Keys are evaluated in sorted order so func retrievers with side
+// effects behave reproducibly. Use OrderedModel to control the order. Computed keys are evaluated last.
+func newModelPlan(model Model) (modelPlan, error) {
+  sorted := sortedKeys(model)
+  plan := modelPlan{keys: make([]string, 0, len(sorted))}
+
+  for _,key := range(sorted) {
+    if !isComputed(model[key]) {
+      plan.keys = append(plan.keys, key)
+    }
+  }
+
+  if len(plan.keys) == len(sorted) {
+    return plan, nil
+  }
+
+  var err error
+  plan.order, err = computedOrder(sorted, func (key string) (value interface{}, exists bool) {
+    value, exists = model[key]
+    return
+  })
+
+  return plan, err
+}
+
+// plan returns the plan of model, reusing the one made by Compile if a compiled model is being extracted.
+func (sc *scraper) plan(model Model) (modelPlan, error) {
+  if sc.compiled != nil {
+    if plan,exists := sc.compiled.plans[reflect.ValueOf(model).Pointer()]; exists {
+      return plan, nil
+    }
+  }
+
+  return newModelPlan(model)
+}
+
+func sortedKeys(model Model) []string {
+  keys := make([]string, 0, len(model))
+
+  for key := range(model) {
+    keys = append(keys, key)
+  }
+
+  sort.Strings(keys)
+  return keys
+}
@@ -0,0 +1,488 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package server exposes dtoo scraping as a JSON HTTP API, so services written in other languages can run
+dtoo data models. Models are serialized as described by dtoo.DecodeModel.
+
+	POST /scrape          {"iterator": ".post", "model": {...}, "url": "https://..." or "html": "<html>...", "options": {...}}
+	POST /models/{name}   {"url": "https://..."} or {"html": "<html>..."}
+	GET  /models
+
+POST /scrape runs the model in the request body. POST /models/{name} runs a model preloaded with
+Options.Models, which keeps large models out of every request, and GET /models lists them. Scrapes reply
+with {"results": [...]} and failures with {"error": "..."} and, for invalid models, a "problems" list.
+
+Every scrape, fetching and extraction included, is limited to Options.Timeout. Extraction stops at the
+next item once the timeout passes, and at most Options.MaxConcurrent scrapes run at once. Request bodies
+are limited to Options.MaxBodyBytes and documents to Options.MaxDocumentBytes.
+
+Only http and https URLs are fetched and, by default, not from hosts on loopback, private or link-local
+addresses, so callers can't reach internal services through the server. Options.AllowUrl restricts the
+URLs fetched further, i.e. to an allow-list of hosts.
+
+	handler := server.New(server.Options{Models: models, Timeout: 10 * time.Second})
+	log.Fatal(http.ListenAndServe(":8080", handler))
+
+The dtoo command serves the same API with "dtoo serve".
+*/
+package server
+
+import (
+  "io"
+  "sort"
+  "errors"
+  "net"
+  "time"
+  "context"
+  "syscall"
+  "net/http"
+  "net/url"
+  "encoding/json"
+  "github.com/dschnare/dtoo"
+)
+
+const (
+  // DefaultTimeout is the time a scrape may take when Options.Timeout is not set.
+  DefaultTimeout = 30 * time.Second
+  // DefaultMaxDocumentBytes is the document size limit when Options.MaxDocumentBytes is not set.
+  DefaultMaxDocumentBytes = 10 << 20
+  // BodyOverhead is the room left in a request body for everything but its document when
+  // Options.MaxBodyBytes is not set.
+  BodyOverhead = 1 << 20
+  // DefaultMaxConcurrent is the number of scrapes run at once when Options.MaxConcurrent is not set.
+  DefaultMaxConcurrent = 16
+)
+
+var (
+  // ErrDocumentTooLarge is returned when a document is larger than Options.MaxDocumentBytes.
+  ErrDocumentTooLarge = errors.New("Document too large")
+  // ErrUrlNotAllowed is returned when a URL, or a URL redirected to, is rejected by Options.AllowUrl.
+  ErrUrlNotAllowed = errors.New("Url not allowed")
+  // ErrAddressNotAllowed is returned when a URL resolves to a loopback, private, link-local or
+  // unspecified address and Options.AllowPrivateNetworks is not set.
+  ErrAddressNotAllowed = errors.New("Address not allowed")
+)
+
+// NamedModel is a data model preloaded into the server under a name.
+type NamedModel struct {
+  Iterator string
+  Model interface{}
+  Options dtoo.ScrapeOptions
+}
+
+// Options configures a Server.
+type Options struct {
+  // The models addressable by name with POST /models/{name}.
+  Models map[string]NamedModel
+  // The time a scrape may take, fetching its document and extraction included. Defaults to DefaultTimeout.
+  Timeout time.Duration
+  // The maximum size of a request body. Defaults to MaxDocumentBytes plus BodyOverhead, so it doesn't
+  // limit posted HTML more than MaxDocumentBytes unless the HTML needs a lot of JSON escaping.
+  MaxBodyBytes int64
+  // The maximum size of a document, fetched or posted. Posted HTML is also bounded by MaxBodyBytes.
+  // Defaults to DefaultMaxDocumentBytes.
+  MaxDocumentBytes int64
+  // The client documents are fetched with. If it has no Transport, or there is no client, then documents
+  // are fetched with a transport that connects directly, without proxies, and refuses addresses on
+  // private networks unless AllowPrivateNetworks is set. A Transport of its own must guard against
+  // requests to internal services itself.
+  Client *http.Client
+  // Reports whether a URL may be fetched, including the URLs of redirects. Only http and https URLs are
+  // ever fetched. If nil then every http and https URL may be.
+  AllowUrl func(u *url.URL) bool
+  // If true then URLs that resolve to loopback, private, link-local or unspecified addresses, such as
+  // localhost or cloud metadata endpoints, may be fetched.
+  AllowPrivateNetworks bool
+  // The maximum number of scrapes run at once. Further requests wait for a scrape to finish, for up to
+  // Timeout. Defaults to DefaultMaxConcurrent.
+  MaxConcurrent int
+}
+
+// Server is an http.Handler that serves the scraping API.
+type Server struct {
+  opts Options
+  client *http.Client
+  mux *http.ServeMux
+  // Holds a token for every scrape running.
+  slots chan struct{}
+}
+
+// New returns a Server configured by opts.
+func New(opts Options) *Server {
+  if opts.Timeout <= 0 {
+    opts.Timeout = DefaultTimeout
+  }
+
+  if opts.MaxDocumentBytes <= 0 {
+    opts.MaxDocumentBytes = DefaultMaxDocumentBytes
+  }
+
+  if opts.MaxBodyBytes <= 0 {
+    opts.MaxBodyBytes = opts.MaxDocumentBytes + BodyOverhead
+  }
+
+  if opts.MaxConcurrent <= 0 {
+    opts.MaxConcurrent = DefaultMaxConcurrent
+  }
+
+  client := http.Client{}
+
+  if opts.Client != nil {
+    client = *opts.Client
+  }
+
+  transport := client.Transport
+
+  if transport == nil {
+    if opts.AllowPrivateNetworks {
+      transport = http.DefaultTransport
+    } else {
+      dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: publicAddressesOnly}
+      t := http.DefaultTransport.(*http.Transport).Clone()
+      // A proxy would be dialed instead of the addresses being checked.
+      t.Proxy = nil
+      t.DialContext = dialer.DialContext
+      transport = t
+    }
+  }
+
+  client.Transport = limitedTransport{base: transport, limit: opts.MaxDocumentBytes}
+  checkRedirect := client.CheckRedirect
+  client.CheckRedirect = func (req *http.Request, via []*http.Request) error {
+    if !opts.allowed(req.URL) {
+      return ErrUrlNotAllowed
+    }
+
+    if checkRedirect != nil {
+      return checkRedirect(req, via)
+    }
+
+    // The default policy of http.Client.
+    if len(via) >= 10 {
+      return errors.New("stopped after 10 redirects")
+    }
+
+    return nil
+  }
+
+  s := &Server{opts: opts, client: &client, mux: http.NewServeMux(), slots: make(chan struct{}, opts.MaxConcurrent)}
+  s.mux.HandleFunc("POST /scrape", s.handleScrape)
+  s.mux.HandleFunc("POST /models/{name}", s.handleNamedScrape)
+  s.mux.HandleFunc("GET /models", s.handleModels)
+
+  return s
+}
+
+// allowed reports whether u may be fetched.
+func (o Options) allowed(u *url.URL) bool {
+  if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+    return false
+  }
+
+  return o.AllowUrl == nil || o.AllowUrl(u)
+}
+
+// publicAddressesOnly is a net.Dialer Control func that refuses to connect to addresses on private
+// networks. It runs after name resolution so hosts that resolve to private addresses are refused too.
+func publicAddressesOnly(network string, address string, c syscall.RawConn) error {
+  host, _, err := net.SplitHostPort(address)
+
+  if err != nil {
+    return err
+  }
+
+  ip := net.ParseIP(host)
+
+  if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+    ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+    return ErrAddressNotAllowed
+  }
+
+  return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s.mux.ServeHTTP(w, r)
+}
+
+// ScrapeRequest is the body of POST /scrape. Only Url and Html are read by POST /models/{name}.
+type ScrapeRequest struct {
+  Iterator string `json:"iterator"`
+  Model json.RawMessage `json:"model"`
+  Url string `json:"url"`
+  Html string `json:"html"`
+  Options dtoo.JSONOptions `json:"options"`
+}
+
+/*
+LoadModels reads named models from a JSON object that maps each name to the iterator, model and options
+of a ScrapeRequest. Every model is decoded with dtoo.DecodeModel.
+
+	{"posts": {"iterator": ".post", "model": {"Title": {"sel": ".post-title", "method": "text"}}, "options": {"limit": 10}}}
+*/
+func LoadModels(r io.Reader) (map[string]NamedModel, error) {
+  requests := map[string]ScrapeRequest{}
+
+  if err := json.NewDecoder(r).Decode(&requests); err != nil {
+    return nil, err
+  }
+
+  models := make(map[string]NamedModel, len(requests))
+
+  for name,req := range(requests) {
+    if model,err := dtoo.DecodeModel(req.Model); err == nil {
+      models[name] = NamedModel{Iterator: req.Iterator, Model: model, Options: req.Options.ScrapeOptions()}
+    } else {
+      return nil, errors.New(name + ": " + err.Error())
+    }
+  }
+
+  return models, nil
+}
+
+func (s *Server) handleScrape(w http.ResponseWriter, r *http.Request) {
+  req, ok := s.readRequest(w, r)
+
+  if !ok {
+    return
+  }
+
+  if len(req.Model) == 0 {
+    writeError(w, http.StatusBadRequest, errors.New("No model"))
+    return
+  }
+
+  model, err := dtoo.DecodeModel(req.Model)
+
+  if err != nil {
+    writeError(w, http.StatusBadRequest, err)
+    return
+  }
+
+  s.scrape(w, r, NamedModel{Iterator: req.Iterator, Model: model, Options: req.Options.ScrapeOptions()}, req)
+}
+
+func (s *Server) handleNamedScrape(w http.ResponseWriter, r *http.Request) {
+  named, exists := s.opts.Models[r.PathValue("name")]
+
+  if !exists {
+    writeError(w, http.StatusNotFound, errors.New("Unknown model " + r.PathValue("name")))
+    return
+  }
+
+  if req,ok := s.readRequest(w, r); ok {
+    s.scrape(w, r, named, req)
+  }
+}
+
+// ModelInfo describes a named model in the reply of GET /models.
+type ModelInfo struct {
+  Name string `json:"name"`
+  Iterator string `json:"iterator"`
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+  models := make([]ModelInfo, 0, len(s.opts.Models))
+
+  for name,named := range(s.opts.Models) {
+    models = append(models, ModelInfo{Name: name, Iterator: named.Iterator})
+  }
+
+  sort.Slice(models, func (i, j int) bool {
+    return models[i].Name < models[j].Name
+  })
+
+  writeJSON(w, http.StatusOK, map[string]interface{}{"models": models})
+}
+
+// readRequest decodes the body of r, replying with an error and returning false if it is invalid.
+func (s *Server) readRequest(w http.ResponseWriter, r *http.Request) (ScrapeRequest, bool) {
+  req := ScrapeRequest{}
+  decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes))
+  decoder.DisallowUnknownFields()
+
+  if err := decoder.Decode(&req); err != nil {
+    var tooLarge *http.MaxBytesError
+
+    if errors.As(err, &tooLarge) {
+      writeError(w, http.StatusRequestEntityTooLarge, errors.New("Request body too large"))
+    } else {
+      writeError(w, http.StatusBadRequest, err)
+    }
+
+    return req, false
+  }
+
+  if (req.Url == "") == (req.Html == "") {
+    writeError(w, http.StatusBadRequest, errors.New("Exactly one of url or html is required"))
+    return req, false
+  }
+
+  if req.Url != "" {
+    if u,err := url.Parse(req.Url); err != nil || !s.opts.allowed(u) {
+      writeError(w, http.StatusForbidden, ErrUrlNotAllowed)
+      return req, false
+    }
+  }
+
+  if int64(len(req.Html)) > s.opts.MaxDocumentBytes {
+    writeError(w, http.StatusRequestEntityTooLarge, ErrDocumentTooLarge)
+    return req, false
+  }
+
+  return req, true
+}
+
+// scrape runs named against the url or html of req within the timeout and replies with the results.
+func (s *Server) scrape(w http.ResponseWriter, r *http.Request, named NamedModel, req ScrapeRequest) {
+  ctx, cancel := context.WithTimeout(r.Context(), s.opts.Timeout)
+
+  select {
+    case s.slots <- struct{}{}:
+    case <-ctx.Done():
+      cancel()
+      writeError(w, http.StatusServiceUnavailable, errors.New("Too many scrapes"))
+      return
+  }
+
+  opts := named.Options
+  opts.Client = s.client
+
+  type scraped struct {
+    results []interface{}
+    err error
+  }
+
+  // Buffered so the scrape can finish after a timeout without blocking. It stops extracting once ctx is
+  // done and only then frees its slot, so scrapes that timed out can't pile up.
+  done := make(chan scraped, 1)
+
+  go func () {
+    defer cancel()
+    defer func () { <-s.slots }()
+
+    var result scraped
+
+    if req.Url != "" {
+      result.results, result.err = dtoo.ScrapeFromUrlWithContext(ctx, named.Iterator, named.Model, req.Url, opts)
+    } else {
+      result.results, result.err = dtoo.ScrapeFromStringWithContext(ctx, named.Iterator, named.Model, req.Html, opts)
+    }
+
+    done <- result
+  }()
+
+  var result scraped
+
+  select {
+    case result = <-done:
+    case <-ctx.Done():
+      result.err = ctx.Err()
+  }
+
+  if result.err != nil {
+    writeError(w, statusOf(result.err), result.err)
+  } else {
+    if result.results == nil {
+      result.results = []interface{}{}
+    }
+
+    writeJSON(w, http.StatusOK, map[string]interface{}{"results": result.results})
+  }
+}
+
+// statusOf returns the status code that reports a scrape error.
+func statusOf(err error) int {
+  var urlErr *url.Error
+  var statusErr *dtoo.StatusError
+
+  switch {
+    case errors.Is(err, context.DeadlineExceeded):
+      return http.StatusGatewayTimeout
+    case errors.Is(err, ErrUrlNotAllowed), errors.Is(err, ErrAddressNotAllowed):
+      return http.StatusForbidden
+    case errors.Is(err, ErrDocumentTooLarge), errors.As(err, &urlErr), errors.As(err, &statusErr):
+      return http.StatusBadGateway
+  }
+
+  return http.StatusUnprocessableEntity
+}
+
+// problem is a ModelError in an error reply.
+type problem struct {
+  Path string `json:"path"`
+  Message string `json:"message"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+  reply := map[string]interface{}{"error": err.Error()}
+  var problems dtoo.ValidationError
+  var modelErr dtoo.ModelError
+
+  if errors.As(err, &modelErr) {
+    problems = dtoo.ValidationError{modelErr}
+  } else {
+    errors.As(err, &problems)
+  }
+
+  if len(problems) > 0 {
+    list := make([]problem, len(problems))
+
+    for i,p := range(problems) {
+      list[i] = problem{p.Path, p.Message}
+    }
+
+    reply["problems"] = list
+  }
+
+  writeJSON(w, status, reply)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(status)
+  json.NewEncoder(w).Encode(value)
+}
+
+// limitedTransport fails the reading of response bodies larger than limit with ErrDocumentTooLarge.
+type limitedTransport struct {
+  base http.RoundTripper
+  limit int64
+}
+
+func (t limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+  resp, err := t.base.RoundTrip(req)
+
+  if err == nil {
+    resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: t.limit}
+  }
+
+  return resp, err
+}
+
+type limitedBody struct {
+  io.ReadCloser
+  remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+  if b.remaining < 0 {
+    return 0, ErrDocumentTooLarge
+  }
+
+  // Read one byte past the limit to tell a document of exactly the limit from a larger one.
+  if int64(len(p)) > b.remaining + 1 {
+    p = p[:b.remaining + 1]
+  }
+
+  n, err := b.ReadCloser.Read(p)
+  b.remaining -= int64(n)
+
+  if b.remaining < 0 {
+    return n + int(b.remaining), ErrDocumentTooLarge
+  }
+
+  return n, err
+}
@@ -0,0 +1,215 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+  "fmt"
+  "time"
+  "strings"
+  "testing"
+  "net/url"
+  "net/http"
+  "io/ioutil"
+  "net/http/httptest"
+  "github.com/PuerkitoBio/goquery"
+  "github.com/dschnare/dtoo"
+)
+
+const posts = `<div class="post" id="1"><h2>First</h2></div><div class="post" id="2"><h2>Second</h2></div>`
+
+func post(t *testing.T, handler http.Handler, path string, body string) (int, string) {
+  rec := httptest.NewRecorder()
+  handler.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(body)))
+
+  return rec.Code, strings.TrimSpace(rec.Body.String())
+}
+
+func TestScrape(t *testing.T) {
+  site := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    fmt.Fprint(w, posts)
+  }))
+  defer site.Close()
+
+  handler := New(Options{AllowPrivateNetworks: true})
+  model := `{"Id": "id", "Title": {"sel": "h2", "method": "text"}}`
+  expected := `{"results":[{"Id":"1","Title":"First"},{"Id":"2","Title":"Second"}]}`
+
+  if code,body := post(t, handler, "/scrape", `{"iterator": ".post", "model": ` + model + `, "html": ` + fmt.Sprintf("%q", posts) + `}`); code != 200 || body != expected {
+    t.Fatalf("invalid html reply: %v %v", code, body)
+  }
+
+  if code,body := post(t, handler, "/scrape", `{"iterator": ".post", "model": ` + model + `, "url": "` + site.URL + `", "options": {"limit": 1}}`); code != 200 || body != `{"results":[{"Id":"1","Title":"First"}]}` {
+    t.Fatalf("invalid url reply: %v %v", code, body)
+  }
+}
+
+func TestNamedModels(t *testing.T) {
+  models, err := LoadModels(strings.NewReader(`{"posts": {"iterator": ".post", "model": "id", "options": {"reverse": true}}}`))
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  handler := New(Options{Models: models})
+
+  if code,body := post(t, handler, "/models/posts", `{"html": ` + fmt.Sprintf("%q", posts) + `}`); code != 200 || body != `{"results":["2","1"]}` {
+    t.Fatalf("invalid reply: %v %v", code, body)
+  }
+
+  if code,body := post(t, handler, "/models/comments", `{"html": "<p></p>"}`); code != 404 || body != `{"error":"Unknown model comments"}` {
+    t.Fatalf("invalid reply: %v %v", code, body)
+  }
+
+  rec := httptest.NewRecorder()
+  handler.ServeHTTP(rec, httptest.NewRequest("GET", "/models", nil))
+
+  if body := strings.TrimSpace(rec.Body.String()); body != `{"models":[{"name":"posts","iterator":".post"}]}` {
+    t.Fatalf("invalid models: %v", body)
+  }
+
+  if _,err := LoadModels(strings.NewReader(`{"posts": {"iterator": ".post", "model": 1}}`)); err == nil || err.Error() != "posts: model: cannot decode a number as a data model" {
+    t.Fatalf("invalid error: %v", err)
+  }
+}
+
+func TestErrors(t *testing.T) {
+  site := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    fmt.Fprint(w, strings.Repeat(posts, 100))
+  }))
+  defer site.Close()
+
+  slow := func (s *goquery.Selection, ctx *dtoo.ScrapeContext) (interface{}, error) {
+    select {
+      case <-time.After(time.Second):
+        return nil, nil
+      case <-ctx.Context.Done():
+        return nil, ctx.Context.Err()
+    }
+  }
+
+  handler := New(Options{MaxBodyBytes: 1024, MaxDocumentBytes: 512, Timeout: 50 * time.Millisecond, AllowPrivateNetworks: true, Models: map[string]NamedModel{
+    "slow": {Iterator: ".post", Model: slow},
+  }})
+
+  tests := []struct {
+    path string
+    body string
+    code int
+    expected string
+  }{
+    {"/scrape", `{"iterator": ".post", "model": "id"}`, 400, `{"error":"Exactly one of url or html is required"}`},
+    {"/scrape", `{"iterator": ".post", "html": "<p></p>"}`, 400, `{"error":"No model"}`},
+    {"/scrape", `{"iterator": ".post", "model": {"Title": {"sel": "h2", "method": "txt"}}, "html": "<p></p>"}`, 400,
+      `{"error":"Title: unrecognized method \"txt\"","problems":[{"path":"Title","message":"unrecognized method \"txt\""}]}`},
+    {"/scrape", `{"iterator": ".post", "model": "id", "html": "` + strings.Repeat("x", 2048) + `"}`, 413, `{"error":"Request body too large"}`},
+    {"/scrape", `{"iterator": ".post", "model": "id", "html": "` + strings.Repeat("x", 600) + `"}`, 413, `{"error":"Document too large"}`},
+    {"/scrape", `{"iterator": ".post", "model": "id", "url": "` + site.URL + `"}`, 502, `{"error":"Document too large"}`},
+    {"/models/slow", `{"html": ` + fmt.Sprintf("%q", posts) + `}`, 504, `{"error":"context deadline exceeded"}`},
+  }
+
+  for _,test := range(tests) {
+    if code,body := post(t, handler, test.path, test.body); code != test.code || body != test.expected {
+      t.Fatalf("invalid reply for %v: expected %v %v got %v %v", test.body, test.code, test.expected, code, body)
+    }
+  }
+}
+
+func TestDefaultBodyLimit(t *testing.T) {
+  // The body limit leaves room for the document so posted HTML is limited by MaxDocumentBytes.
+  handler := New(Options{MaxDocumentBytes: 512})
+
+  if code,body := post(t, handler, "/scrape", `{"iterator": ".post", "model": "id", "html": "` + strings.Repeat("x", 600) + `"}`); code != 413 || body != `{"error":"Document too large"}` {
+    t.Fatalf("invalid reply: %v %v", code, body)
+  }
+}
+
+func TestUrlRestrictions(t *testing.T) {
+  site := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path == "/redirect" {
+      http.Redirect(w, r, "/admin", http.StatusFound)
+      return
+    }
+
+    fmt.Fprint(w, posts)
+  }))
+  defer site.Close()
+
+  scrape := func (handler http.Handler, url string) (int, string) {
+    return post(t, handler, "/scrape", `{"iterator": ".post", "model": "id", "url": "` + url + `"}`)
+  }
+
+  // Loopback addresses are refused by default.
+  if code,body := scrape(New(Options{}), site.URL); code != 403 || !strings.Contains(body, "Address not allowed") {
+    t.Fatalf("invalid reply: %v %v", code, body)
+  }
+
+  noAdmin := New(Options{AllowPrivateNetworks: true, AllowUrl: func (u *url.URL) bool {
+    return !strings.HasPrefix(u.Path, "/admin")
+  }})
+
+  tests := []struct {
+    url string
+    code int
+  }{
+    {site.URL, 200},
+    {site.URL + "/admin", 403},
+    {site.URL + "/redirect", 403},
+    {"file:///etc/passwd", 403},
+    {"gopher://localhost/", 403},
+  }
+
+  for _,test := range(tests) {
+    if code,body := scrape(noAdmin, test.url); code != test.code {
+      t.Fatalf("invalid reply for %v: expected %v got %v %v", test.url, test.code, code, body)
+    }
+  }
+}
+
+func TestMaxConcurrent(t *testing.T) {
+  release := make(chan bool)
+  // Ignores the timeout so it holds its slot until released.
+  stuck := func (s *goquery.Selection) (interface{}, error) {
+    <-release
+    return nil, nil
+  }
+
+  handler := New(Options{MaxConcurrent: 1, Timeout: 50 * time.Millisecond, Models: map[string]NamedModel{
+    "stuck": {Iterator: ".post", Model: stuck},
+  }})
+
+  if code,body := post(t, handler, "/models/stuck", `{"html": ` + fmt.Sprintf("%q", posts) + `}`); code != 504 {
+    t.Fatalf("invalid reply: %v %v", code, body)
+  }
+
+  // The timed out scrape still holds the only slot.
+  if code,body := post(t, handler, "/scrape", `{"iterator": ".post", "model": "id", "html": "<p></p>"}`); code != 503 || body != `{"error":"Too many scrapes"}` {
+    t.Fatalf("invalid reply: %v %v", code, body)
+  }
+
+  close(release)
+  deadline := time.Now().Add(5 * time.Second)
+
+  for {
+    if code,_ := post(t, handler, "/scrape", `{"iterator": ".post", "model": "id", "html": "<p></p>"}`); code == 200 {
+      break
+    } else if time.Now().After(deadline) {
+      t.Fatalf("the slot was not freed: %v", code)
+    }
+  }
+}
+
+func TestLimitedBody(t *testing.T) {
+  body := &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("12345")), remaining: 5}
+
+  if data,err := ioutil.ReadAll(body); err != nil || string(data) != "12345" {
+    t.Fatalf("invalid body: %q %v", data, err)
+  }
+
+  body = &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("123456")), remaining: 5}
+
+  if data,err := ioutil.ReadAll(body); err != ErrDocumentTooLarge || string(data) != "12345" {
+    t.Fatalf("invalid body: %q %v", data, err)
+  }
+}
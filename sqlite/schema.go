@@ -0,0 +1,303 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+  "fmt"
+  "sort"
+  "bytes"
+  "reflect"
+  "strings"
+  "database/sql"
+  "encoding/json"
+  "github.com/dschnare/dtoo"
+)
+
+// cell is a column of a row and its value.
+type cell struct {
+  name string
+  value interface{}
+}
+
+// row is the columns of an item in order.
+type row []cell
+
+func (r row) get(name string) interface{} {
+  for _,c := range(r) {
+    if c.name == name {
+      return c.value
+    }
+  }
+
+  return nil
+}
+
+// toRow returns the columns of item. Model and map keys are ordered by name and OrderedModel keys keep
+// their order. Structs are converted through their JSON form.
+func toRow(item interface{}) (row, error) {
+  switch v := item.(type) {
+    case dtoo.Model:
+      return mapRow(v), nil
+    case map[string]interface{}:
+      return mapRow(v), nil
+    case dtoo.OrderedModel:
+      r := make(row, len(v))
+
+      for i,field := range(v) {
+        r[i] = cell{field.Key, field.Value}
+      }
+
+      return r, nil
+  }
+
+  if v := reflect.ValueOf(item); v.Kind() == reflect.Struct || (v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct) {
+    data, err := json.Marshal(item)
+
+    if err != nil {
+      return nil, err
+    }
+
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.UseNumber()
+    fields := map[string]interface{}{}
+
+    if err = decoder.Decode(&fields); err != nil {
+      return nil, err
+    }
+
+    return mapRow(fields), nil
+  }
+
+  return nil, fmt.Errorf("Unsupported item type %T", item)
+}
+
+func mapRow(m map[string]interface{}) row {
+  keys := make([]string, 0, len(m))
+
+  for key := range(m) {
+    keys = append(keys, key)
+  }
+
+  sort.Strings(keys)
+  r := make(row, len(keys))
+
+  for i,key := range(keys) {
+    r[i] = cell{key, m[key]}
+  }
+
+  return r
+}
+
+// sliceElements returns the elements of value if it is a slice, other than an OrderedModel or []byte.
+func sliceElements(value interface{}) ([]interface{}, bool) {
+  switch value.(type) {
+    case nil, dtoo.OrderedModel, []byte:
+      return nil, false
+  }
+
+  v := reflect.ValueOf(value)
+
+  if v.Kind() != reflect.Slice {
+    return nil, false
+  }
+
+  elements := make([]interface{}, v.Len())
+
+  for i := range(elements) {
+    elements[i] = v.Index(i).Interface()
+  }
+
+  return elements, true
+}
+
+// columnType returns the declared type of a column inferred from value. Nil values have no declared type.
+func columnType(value interface{}) string {
+  switch value.(type) {
+    case nil:
+      return ""
+    case json.Number:
+      return "NUMERIC"
+  }
+
+  switch reflect.ValueOf(value).Kind() {
+    case reflect.String:
+      return "TEXT"
+    case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+      reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      return "INTEGER"
+    case reflect.Float32, reflect.Float64:
+      return "REAL"
+  }
+
+  // Everything else is stored as JSON text.
+  return "TEXT"
+}
+
+// sqlValue returns value in a form the driver accepts. Models, slices and other composite values are
+// encoded as JSON text.
+func sqlValue(value interface{}) interface{} {
+  switch v := value.(type) {
+    case nil, string, bool, int, int64, float64:
+      return v
+    case json.Number:
+      if i,err := v.Int64(); err == nil {
+        return i
+      } else if f,err := v.Float64(); err == nil {
+        return f
+      }
+
+      return v.String()
+  }
+
+  switch v := reflect.ValueOf(value); v.Kind() {
+    case reflect.String:
+      return v.String()
+    case reflect.Bool:
+      return v.Bool()
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      return v.Int()
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      return int64(v.Uint())
+    case reflect.Float32, reflect.Float64:
+      return v.Float()
+  }
+
+  if data,err := json.Marshal(value); err == nil {
+    return string(data)
+  }
+
+  return fmt.Sprint(value)
+}
+
+// quote quotes a SQL identifier.
+func quote(name string) string {
+  return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// ensureTable creates table if it does not exist and adds any columns of r it is missing. If keys are
+// specified then a unique index on them is created so rows can be upserted by them.
+func (s *Sink) ensureTable(tx *sql.Tx, table string, r row, keys []string) error {
+  columns, known := s.columns[table]
+
+  if !known {
+    var err error
+
+    if columns,err = tableColumns(tx, table); err != nil {
+      return err
+    }
+
+    if len(columns) == 0 {
+      definitions := make([]string, len(r))
+
+      for i,c := range(r) {
+        definitions[i] = strings.TrimSpace(quote(c.name) + " " + columnType(c.value))
+        columns[strings.ToLower(c.name)] = true
+      }
+
+      if _,err = tx.Exec("CREATE TABLE " + quote(table) + " (" + strings.Join(definitions, ", ") + ")"); err != nil {
+        return err
+      }
+    }
+
+    if len(keys) > 0 {
+      quoted := make([]string, len(keys))
+
+      for i,key := range(keys) {
+        quoted[i] = quote(key)
+      }
+
+      index := quote(table + "_" + strings.Join(keys, "_"))
+
+      if _,err = tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + index + " ON " + quote(table) + " (" + strings.Join(quoted, ", ") + ")"); err != nil {
+        return err
+      }
+    }
+
+    s.columns[table] = columns
+  }
+
+  for _,c := range(r) {
+    if !columns[strings.ToLower(c.name)] {
+      if _,err := tx.Exec(strings.TrimSpace("ALTER TABLE " + quote(table) + " ADD COLUMN " + quote(c.name) + " " + columnType(c.value))); err != nil {
+        return err
+      }
+
+      columns[strings.ToLower(c.name)] = true
+    }
+  }
+
+  return nil
+}
+
+// tableColumns returns the lower case names of the columns of table, or no names if it does not exist.
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+  rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+
+  if err != nil {
+    return nil, err
+  }
+
+  defer rows.Close()
+  columns := map[string]bool{}
+
+  for rows.Next() {
+    var name string
+
+    if err := rows.Scan(&name); err != nil {
+      return nil, err
+    }
+
+    columns[strings.ToLower(name)] = true
+  }
+
+  return columns, rows.Err()
+}
+
+// upsert inserts r into table, replacing the values of the row with the same keys if there is one.
+// Columns the row has that r does not are left unchanged. If no keys are specified then r is inserted.
+func (s *Sink) upsert(tx *sql.Tx, table string, r row, keys []string) error {
+  if err := s.ensureTable(tx, table, r, keys); err != nil {
+    return err
+  }
+
+  names := make([]string, len(r))
+  placeholders := make([]string, len(r))
+  values := make([]interface{}, len(r))
+  updates := make([]string, 0, len(r))
+  isKey := map[string]bool{}
+
+  for _,key := range(keys) {
+    isKey[key] = true
+  }
+
+  for i,c := range(r) {
+    names[i] = quote(c.name)
+    placeholders[i] = "?"
+    values[i] = sqlValue(c.value)
+
+    if !isKey[c.name] {
+      updates = append(updates, names[i] + " = excluded." + names[i])
+    }
+  }
+
+  query := "INSERT INTO " + quote(table) + " (" + strings.Join(names, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+
+  if len(keys) > 0 {
+    quoted := make([]string, len(keys))
+
+    for i,key := range(keys) {
+      quoted[i] = quote(key)
+    }
+
+    if len(updates) > 0 {
+      query += " ON CONFLICT (" + strings.Join(quoted, ", ") + ") DO UPDATE SET " + strings.Join(updates, ", ")
+    } else {
+      query += " ON CONFLICT (" + strings.Join(quoted, ", ") + ") DO NOTHING"
+    }
+  }
+
+  _,err := tx.Exec(query, values...)
+  return err
+}
@@ -0,0 +1,216 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+/*
+Package sqlite writes the results of dtoo scrapes into SQLite tables, creating and migrating the tables
+to fit the results. It uses the pure Go modernc.org/sqlite driver so no C toolchain is needed.
+
+A Sink infers a column for every key of the items it writes and adds the columns a table is missing
+before writing. Items are upserted by the value of Options.Key, so scraping the same listing repeatedly
+keeps one row per item. Nested Models are stored as JSON text. Slices, such as the results of recursive
+scrapes, are stored as JSON text too or, with Options.ChildTables, as rows of a child table.
+
+  db, err := sqlite.Open("games.db")
+  sink := sqlite.NewSink(db, sqlite.Options{Table: "games", Key: "Id", ChildTables: true})
+  err = sink.WriteAll(games)
+
+A Sink is also an export.Writer, so results can be written as they arrive from dtoo.ScrapeBatch with
+export.WriteBatch.
+*/
+package sqlite
+
+import (
+  "errors"
+  "strings"
+  "database/sql"
+  _ "modernc.org/sqlite"
+)
+
+// Open opens the SQLite database file at path, creating it if it does not exist.
+func Open(path string) (*sql.DB, error) {
+  return sql.Open("sqlite", path)
+}
+
+// Options specifies the table a Sink writes to and how items are stored in it.
+type Options struct {
+  // The table items are written to. Required.
+  Table string
+  // The key that identifies an item. Items are upserted by its value, which must be set for every item.
+  // If empty then every item is inserted as a new row.
+  Key string
+  // If true then slices are stored as rows of a child table named <Table>_<key> instead of as JSON text.
+  // Child rows hold the Key of their parent in a "_parent" column, their index in a "_position" column
+  // and either the keys of the element if it is a Model or the element itself in a "value" column. Elements
+  // with a _parent or _position key are rejected. Requires Key.
+  ChildTables bool
+}
+
+// Sink writes items into a SQLite table. It is not safe for concurrent use.
+type Sink struct {
+  db *sql.DB
+  opts Options
+  // The columns of each table written to so far, so each table is only inspected once.
+  columns map[string]map[string]bool
+}
+
+// NewSink returns a Sink that writes items to db according to opts.
+func NewSink(db *sql.DB, opts Options) *Sink {
+  return &Sink{db: db, opts: opts, columns: map[string]map[string]bool{}}
+}
+
+// Write upserts a single item, which must be a Model, OrderedModel, map or struct.
+func (s *Sink) Write(item interface{}) error {
+  return s.WriteAll([]interface{}{item})
+}
+
+// WriteAll upserts items in a single transaction. Either every item is written or none are.
+func (s *Sink) WriteAll(items []interface{}) error {
+  if s.opts.Table == "" {
+    return errors.New("No table")
+  }
+
+  if s.opts.ChildTables && s.opts.Key == "" {
+    return errors.New("Child tables require a Key")
+  }
+
+  rows := make([]row, len(items))
+
+  for i,item := range(items) {
+    if r,err := toRow(item); err == nil {
+      rows[i] = r
+    } else {
+      return err
+    }
+  }
+
+  tx, err := s.db.Begin()
+
+  if err != nil {
+    return err
+  }
+
+  if err = s.write(tx, rows); err != nil {
+    tx.Rollback()
+    // Forget the columns added in the rolled back transaction.
+    s.columns = map[string]map[string]bool{}
+    return err
+  }
+
+  return tx.Commit()
+}
+
+// Close implements export.Writer. It does not close the database.
+func (s *Sink) Close() error {
+  return nil
+}
+
+func (s *Sink) write(tx *sql.Tx, rows []row) error {
+  for _,r := range(rows) {
+    parent, children, err := s.split(r)
+
+    if err != nil {
+      return err
+    }
+
+    if err = s.upsert(tx, s.opts.Table, parent, s.keys()); err != nil {
+      return err
+    }
+
+    for _,child := range(children) {
+      if err = s.replaceChildren(tx, child, parent.get(s.opts.Key)); err != nil {
+        return err
+      }
+    }
+  }
+
+  return nil
+}
+
+// keys returns the columns items are upserted by.
+func (s *Sink) keys() []string {
+  if s.opts.Key == "" {
+    return nil
+  }
+
+  return []string{s.opts.Key}
+}
+
+// The columns of child rows that hold the Key of their parent and their index. They are prefixed so they
+// don't collide with the keys of the elements.
+const (
+  parentColumn = "_parent"
+  positionColumn = "_position"
+)
+
+// childKeys are the columns child rows are upserted by.
+var childKeys = []string{parentColumn, positionColumn}
+
+// childRows are the rows of the child table of a slice key of an item.
+type childRows struct {
+  table string
+  rows []row
+}
+
+// split separates the slice keys of r into child rows when child tables are enabled.
+func (s *Sink) split(r row) (row, []childRows, error) {
+  if s.opts.Key != "" && r.get(s.opts.Key) == nil {
+    return nil, nil, errors.New("Item has no " + s.opts.Key)
+  }
+
+  if !s.opts.ChildTables {
+    return r, nil, nil
+  }
+
+  parent := make(row, 0, len(r))
+  children := make([]childRows, 0)
+
+  for _,c := range(r) {
+    if elements,ok := sliceElements(c.value); ok {
+      child := childRows{table: s.opts.Table + "_" + c.name, rows: make([]row, len(elements))}
+
+      for i,element := range(elements) {
+        child.rows[i] = row{{parentColumn, nil}, {positionColumn, i}}
+
+        if fields,err := toRow(element); err == nil {
+          for _,f := range(fields) {
+            if name := strings.ToLower(f.name); name == parentColumn || name == positionColumn {
+              return nil, nil, errors.New("Key " + f.name + " of " + c.name + " is reserved for child tables")
+            }
+          }
+
+          child.rows[i] = append(child.rows[i], fields...)
+        } else {
+          child.rows[i] = append(child.rows[i], cell{"value", element})
+        }
+      }
+
+      children = append(children, child)
+    } else {
+      parent = append(parent, c)
+    }
+  }
+
+  return parent, children, nil
+}
+
+// replaceChildren replaces the rows of a child table that belong to the parent with the specified key.
+func (s *Sink) replaceChildren(tx *sql.Tx, child childRows, key interface{}) error {
+  if err := s.ensureTable(tx, child.table, row{{parentColumn, key}, {positionColumn, 0}}, childKeys); err != nil {
+    return err
+  }
+
+  if _,err := tx.Exec("DELETE FROM " + quote(child.table) + " WHERE " + quote(parentColumn) + " = ?", sqlValue(key)); err != nil {
+    return err
+  }
+
+  for _,r := range(child.rows) {
+    r[0].value = key
+
+    if err := s.upsert(tx, child.table, r, childKeys); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
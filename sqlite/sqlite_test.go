@@ -0,0 +1,170 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+  "os"
+  "fmt"
+  "strings"
+  "testing"
+  "io/ioutil"
+  "database/sql"
+  "path/filepath"
+  "github.com/dschnare/dtoo"
+  "github.com/dschnare/dtoo/export"
+)
+
+var _ export.Writer = &Sink{}
+
+func openTestDB(t *testing.T) (*sql.DB, func ()) {
+  dir, _ := ioutil.TempDir("", "dtoo-sqlite")
+  db, err := Open(filepath.Join(dir, "test.db"))
+
+  if err != nil {
+    os.RemoveAll(dir)
+    t.Fatal(err)
+  }
+
+  return db, func () {
+    db.Close()
+    os.RemoveAll(dir)
+  }
+}
+
+// dump returns the rows of a query as text, one row per line with columns separated by "|".
+func dump(t *testing.T, db *sql.DB, query string) string {
+  rows, err := db.Query(query)
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  defer rows.Close()
+  columns, _ := rows.Columns()
+  lines := make([]string, 0)
+
+  for rows.Next() {
+    values := make([]interface{}, len(columns))
+    pointers := make([]interface{}, len(columns))
+
+    for i := range(values) {
+      pointers[i] = &values[i]
+    }
+
+    if err := rows.Scan(pointers...); err != nil {
+      t.Fatal(err)
+    }
+
+    fields := make([]string, len(values))
+
+    for i,value := range(values) {
+      fields[i] = fmt.Sprint(value)
+    }
+
+    lines = append(lines, strings.Join(fields, "|"))
+  }
+
+  return strings.Join(lines, "\n")
+}
+
+func TestSinkJSONColumns(t *testing.T) {
+  db, done := openTestDB(t)
+  defer done()
+
+  sink := NewSink(db, Options{Table: "games", Key: "Id"})
+
+  if err := sink.WriteAll([]interface{}{
+    dtoo.Model{"Id": "570", "Name": "Dota 2", "Metascore": 90, "Genres": []string{"Action", "Strategy"}},
+    dtoo.Model{"Id": "440", "Name": "TF2", "Metascore": 92, "Genres": []string{"Action"}},
+  }); err != nil {
+    t.Fatal(err)
+  }
+
+  // Upserts by Id and adds the new Price column.
+  if err := sink.Write(dtoo.OrderedModel{{Key: "Id", Value: "570"}, {Key: "Metascore", Value: 91}, {Key: "Price", Value: 0.5}}); err != nil {
+    t.Fatal(err)
+  }
+
+  expected := `570|Dota 2|91|["Action","Strategy"]|0.5
+440|TF2|92|["Action"]|<nil>`
+
+  if rows := dump(t, db, `SELECT Id, Name, Metascore, Genres, Price FROM games ORDER BY rowid`); rows != expected {
+    t.Fatalf("invalid rows: expected\n%v\ngot\n%v", expected, rows)
+  }
+
+  // A new Sink picks up the existing schema.
+  if err := NewSink(db, Options{Table: "games", Key: "Id"}).Write(dtoo.Model{"Id": "440", "Price": 1.5}); err != nil {
+    t.Fatal(err)
+  }
+
+  if rows := dump(t, db, `SELECT Price FROM games WHERE Id = '440'`); rows != "1.5" {
+    t.Fatalf("invalid price: %v", rows)
+  }
+}
+
+func TestSinkChildTables(t *testing.T) {
+  db, done := openTestDB(t)
+  defer done()
+
+  type comment struct {
+    Author string
+    Likes int
+  }
+
+  sink := NewSink(db, Options{Table: "posts", Key: "Id", ChildTables: true})
+
+  for _,comments := range([][]interface{}{
+    {dtoo.Model{"Author": "ann", "Likes": 1}, dtoo.Model{"Author": "bob"}, dtoo.Model{"Author": "cat"}},
+    {dtoo.Model{"Author": "ann", "Likes": 2}, comment{Author: "dan", Likes: 3}},
+  }) {
+    if err := sink.Write(dtoo.Model{"Id": 1, "Title": "Post", "Tags": []string{"go"}, "Comments": comments}); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  if rows := dump(t, db, `SELECT _parent, _position, Author, Likes FROM posts_Comments ORDER BY _position`); rows != "1|0|ann|2\n1|1|dan|3" {
+    t.Fatalf("invalid comments: %v", rows)
+  }
+
+  if rows := dump(t, db, `SELECT _parent, _position, value FROM posts_Tags`); rows != "1|0|go" {
+    t.Fatalf("invalid tags: %v", rows)
+  }
+
+  if rows := dump(t, db, `SELECT * FROM posts`); rows != "1|Post" {
+    t.Fatalf("invalid posts: %v", rows)
+  }
+
+  // Elements can have keys named like the columns that link them to their parent.
+  if err := sink.Write(dtoo.Model{"Id": 2, "Links": []interface{}{dtoo.Model{"parent": "home", "position": "top"}}}); err != nil {
+    t.Fatal(err)
+  }
+
+  if rows := dump(t, db, `SELECT _parent, _position, parent, position FROM posts_Links`); rows != "2|0|home|top" {
+    t.Fatalf("invalid links: %v", rows)
+  }
+}
+
+func TestSinkErrors(t *testing.T) {
+  db, done := openTestDB(t)
+  defer done()
+
+  tests := []struct {
+    opts Options
+    item interface{}
+    expected string
+  }{
+    {Options{}, dtoo.Model{"Id": 1}, "No table"},
+    {Options{Table: "t", ChildTables: true}, dtoo.Model{"Id": 1}, "Child tables require a Key"},
+    {Options{Table: "t", Key: "Id"}, dtoo.Model{"Name": "x"}, "Item has no Id"},
+    {Options{Table: "t"}, "x", "Unsupported item type string"},
+    {Options{Table: "t", Key: "Id", ChildTables: true}, dtoo.Model{"Id": 1, "Links": []interface{}{dtoo.Model{"_Position": 1}}}, "Key _Position of Links is reserved for child tables"},
+  }
+
+  for _,test := range(tests) {
+    if err := NewSink(db, test.opts).Write(test.item); err == nil || err.Error() != test.expected {
+      t.Fatalf("invalid error: expected %v got %v", test.expected, err)
+    }
+  }
+}
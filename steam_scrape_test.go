@@ -5,12 +5,8 @@
 package dtoo
 
 import (
-
-  "os"
-  "io"
   "net/http"
   "testing"
-  "bufio"
 
   "strings"
   "regexp"
@@ -29,135 +25,105 @@ type Game struct {
   Genres []string
 }
 
-func downloadSteamFixture() {
-  var err error
-  var file *os.File
-  var resp *http.Response
+func replayClient() *http.Client {
+  transport := &ReplayTransport{Dir: "./fixtures/replay"}
 
-  defer func () {
-    if file != nil {
-      file.Close()
-    }
-  }()
-
-  if file,err = os.Open("./fixtures/steam.html"); err != nil {
-    if file,err = os.Create("./fixtures/steam.html"); err == nil {
-      if resp,err = http.Get("http://store.steampowered.com/search/"); err == nil {
-        var line []byte
-        buf := bufio.NewReader(resp.Body)
-        for err == nil {
-          if line,err = buf.ReadBytes('\n'); err == nil {
-            _,err = file.Write(line)
-          }
-        }
-      }
-    }
+  if UpdateFixtures() {
+    transport.Mode = Record
   }
 
-  if err != nil && err != io.EOF {
-    panic(err)
-  }
+  return &http.Client{Transport: transport}
 }
 
 func TestSteamScrape(t *testing.T) {
-  downloadSteamFixture()
-
   genreRegexp := regexp.MustCompile(`\s*-\s+Released.+`)
   commaRegexp := regexp.MustCompile(`\s*,\s*`)
   idRegexp := regexp.MustCompile(`https?:\/\/store\.steampowered\.com\/app\/([^\/]+?)\/`)
   logoRegexp := regexp.MustCompile(`sm_\d+`)
 
-  if file,err := os.Open("./fixtures/steam.html"); err == nil {
-    if games,err := ScrapeFromReader(".search_result_row", Model{
-      "Id": func (s *goquery.Selection) (interface{}, error) {
-        if href,exists := s.Attr("href"); exists {
-          matches := idRegexp.FindAllStringSubmatch(href, -1)
-          if len(matches) == 1 {
-            return matches[0][1], nil
-          }
-        }
-
-        return "", nil
-      },
-      "Name": RetrieverModel{Sel: ".search_name h4", Method: "text"},
-      "DetailsUrl": "href",
-      "LogoSmall": RetrieverModel{Sel: ".search_capsule img", Attr: "src"},
-      "Metascore": RetrieverModel{Sel: ".search_metascore", Method: "text"},
-      "ReleaseDate": RetrieverModel{Sel: ".search_released", Method: "text"},
-      "Genres": RetrieverModel{
-        Sel: ".search_name p",
-        Method: func (s *goquery.Selection) (interface{}, error) {
-          text := s.Text()
-          text = strings.TrimSpace(text)
-          text = genreRegexp.ReplaceAllLiteralString(text, "")
-          genres := commaRegexp.Split(text, -1)
-          return genres, nil
-        },
-      },
-      "Logo": func (s *goquery.Selection) (interface{}, error) {
-        if src,exists := s.Find(".search_capsule img").Attr("src"); exists {
-          return logoRegexp.ReplaceAllLiteralString(src, "184x69"), nil
+  if games,err := ScrapeFromUrlWithOptions(".search_result_row", Model{
+    "Id": func (s *goquery.Selection) (interface{}, error) {
+      if href,exists := s.Attr("href"); exists {
+        matches := idRegexp.FindAllStringSubmatch(href, -1)
+        if len(matches) == 1 {
+          return matches[0][1], nil
         }
+      }
 
-        return "", nil
+      return "", nil
+    },
+    "Name": RetrieverModel{Sel: ".search_name h4", Method: "text"},
+    "DetailsUrl": "href",
+    "LogoSmall": RetrieverModel{Sel: ".search_capsule img", Attr: "src"},
+    "Metascore": RetrieverModel{Sel: ".search_metascore", Method: "text"},
+    "ReleaseDate": RetrieverModel{Sel: ".search_released", Method: "text"},
+    "Genres": RetrieverModel{
+      Sel: ".search_name p",
+      Method: func (s *goquery.Selection) (interface{}, error) {
+        text := s.Text()
+        text = strings.TrimSpace(text)
+        text = genreRegexp.ReplaceAllLiteralString(text, "")
+        genres := commaRegexp.Split(text, -1)
+        return genres, nil
       },
-    }, file); err == nil {
-      if len(games) != 25 {
-        t.Fatalf("game count invalid: expected %v got %v", 25, len(games))
+    },
+    "Logo": Computed{Deps: []string{"LogoSmall"}, Func: func (data Model) (interface{}, error) {
+      if src,ok := data["LogoSmall"].(string); ok {
+        return logoRegexp.ReplaceAllLiteralString(src, "184x69"), nil
       }
 
-      for i,data := range(games) {
-        if obj,ok := data.(Model); ok {
-          game := toGame(obj)
-
-          switch i {
-            case 0:
-              testGame(t, game, Game{
-                Id: "570",
-                Name: "Dota 2",
-                DetailsUrl: "http://store.steampowered.com/app/570/?snr=1_7_7_230_150_1",
-                LogoSmall: "http://cdn.akamai.steamstatic.com/steam/apps/570/capsule_sm_120.jpg?t=1404424435",
-                Logo: "http://cdn.akamai.steamstatic.com/steam/apps/570/capsule_184x69.jpg?t=1404424435",
-                Metascore: 90,
-                ReleaseDate: "9 Jul 2013",
-                Genres: []string{"Action", "Free to Play", "Strategy"},
-              })
-            case 4:
-              testGame(t, game, Game{
-                Id: "48700",
-                Name: "Mount & Blade: Warband",
-                DetailsUrl: "http://store.steampowered.com/app/48700/?snr=1_7_7_230_150_1",
-                LogoSmall: "http://cdn.akamai.steamstatic.com/steam/apps/48700/capsule_sm_120.jpg?t=1405012491",
-                Logo: "http://cdn.akamai.steamstatic.com/steam/apps/48700/capsule_184x69.jpg?t=1405012491",
-                Metascore: 78,
-                ReleaseDate: "31 Mar 2010",
-                Genres: []string{"Action", "RPG"},
-              })
-            case 24:
-              testGame(t, game, Game{
-                Id: "49520",
-                Name: "Borderlands 2",
-                DetailsUrl: "http://store.steampowered.com/app/49520/?snr=1_7_7_230_150_1",
-                LogoSmall: "http://cdn.akamai.steamstatic.com/steam/apps/49520/capsule_sm_120.jpg?t=1398979144",
-                Logo: "http://cdn.akamai.steamstatic.com/steam/apps/49520/capsule_184x69.jpg?t=1398979144",
-                Metascore: 89,
-                ReleaseDate: "17 Sep 2012",
-                Genres: []string{"Action", "RPG"},
-              })
-          }
-        } else {
-          t.Fatalf("invalid game encountered: %v", data)
-        }
-      }
-    } else {
-      panic(err)
+      return "", nil
+    }},
+  }, "http://store.steampowered.com/search/", ScrapeOptions{Client: replayClient()}); err == nil {
+    if len(games) != 25 {
+      t.Fatalf("game count invalid: expected %v got %v", 25, len(games))
     }
 
-    if file != nil {
-      file.Close()
+    for i,data := range(games) {
+      if obj,ok := data.(Model); ok {
+        game := toGame(obj)
+
+        switch i {
+          case 0:
+            testGame(t, game, Game{
+              Id: "570",
+              Name: "Dota 2",
+              DetailsUrl: "http://store.steampowered.com/app/570/?snr=1_7_7_230_150_1",
+              LogoSmall: "http://cdn.akamai.steamstatic.com/steam/apps/570/capsule_sm_120.jpg?t=1404424435",
+              Logo: "http://cdn.akamai.steamstatic.com/steam/apps/570/capsule_184x69.jpg?t=1404424435",
+              Metascore: 90,
+              ReleaseDate: "9 Jul 2013",
+              Genres: []string{"Action", "Free to Play", "Strategy"},
+            })
+          case 4:
+            testGame(t, game, Game{
+              Id: "48700",
+              Name: "Mount & Blade: Warband",
+              DetailsUrl: "http://store.steampowered.com/app/48700/?snr=1_7_7_230_150_1",
+              LogoSmall: "http://cdn.akamai.steamstatic.com/steam/apps/48700/capsule_sm_120.jpg?t=1405012491",
+              Logo: "http://cdn.akamai.steamstatic.com/steam/apps/48700/capsule_184x69.jpg?t=1405012491",
+              Metascore: 78,
+              ReleaseDate: "31 Mar 2010",
+              Genres: []string{"Action", "RPG"},
+            })
+          case 24:
+            testGame(t, game, Game{
+              Id: "49520",
+              Name: "Borderlands 2",
+              DetailsUrl: "http://store.steampowered.com/app/49520/?snr=1_7_7_230_150_1",
+              LogoSmall: "http://cdn.akamai.steamstatic.com/steam/apps/49520/capsule_sm_120.jpg?t=1398979144",
+              Logo: "http://cdn.akamai.steamstatic.com/steam/apps/49520/capsule_184x69.jpg?t=1398979144",
+              Metascore: 89,
+              ReleaseDate: "17 Sep 2012",
+              Genres: []string{"Action", "RPG"},
+            })
+        }
+      } else {
+        t.Fatalf("invalid game encountered: %v", data)
+      }
     }
   } else {
-    panic(err)
+    t.Fatal(err)
   }
 }
 
@@ -0,0 +1,96 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "errors"
+  "github.com/PuerkitoBio/goquery"
+)
+
+// ErrSkip drops the item being extracted from the results of the innermost iteration. It is returned by the
+// Skip data model and can also be returned by func retrievers, wrapped or not.
+var ErrSkip = errors.New("Skip item")
+
+type skipModel struct{}
+
+// Skip is a data model that drops the item being extracted from the results of the innermost iteration
+// instead of extracting a value. It is usually the Data of a Case or the Default of a Switch.
+var Skip interface{} = skipModel{}
+
+/*
+Switch is a data model that picks the data model of the first Case whose conditions hold for the item
+being extracted, or Default if none do. Default can be nil, in which case nil is extracted.
+
+Example:
+
+Skips ads and extracts videos and articles with different models.
+
+    dtoo.ScrapeFromUrl(".card", dtoo.Switch{
+      Cases: []dtoo.Case{
+        {Is: ".sponsored", Data: dtoo.Skip},
+        {Attr: "data-type", Value: "video", Data: videoModel},
+      },
+      Default: articleModel,
+    }, url)
+*/
+type Switch struct {
+  Cases []Case
+  Default interface{}
+}
+
+// Case is a branch of a Switch. Every condition that is set must hold for the Case to be picked.
+// A Case without conditions is always picked.
+type Case struct {
+  // Holds if the item matches this CSS selector.
+  Is string
+  // Holds if the item has a descendant matching this CSS selector.
+  Has string
+  // Holds if the item has this attribute. If Value is set then the attribute must also equal Value.
+  Attr string
+  Value string
+  // Holds if this func returns true for the item.
+  When func (s *goquery.Selection) bool
+  // The data model extracted if the Case is picked.
+  Data interface{}
+}
+
+func (sc *scraper) extractSwitch(sw Switch, s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
+  for _,c := range(sw.Cases) {
+    if sc.holds(c, s) {
+      return sc.extract(c.Data, s, ctx)
+    }
+  }
+
+  if sw.Default == nil {
+    return nil, nil
+  }
+
+  return sc.extract(sw.Default, s, ctx)
+}
+
+// holds reports whether every condition of c holds for s.
+func (sc *scraper) holds(c Case, s *goquery.Selection) bool {
+  if c.Is != EMPTYSTRING {
+    if m := sc.matcher(c.Is); m != nil {
+      if !s.IsMatcher(m) {
+        return false
+      }
+    } else if !s.Is(c.Is) {
+      return false
+    }
+  }
+
+  if c.Has != EMPTYSTRING && sc.find(s, c.Has).Length() == 0 {
+    return false
+  }
+
+  if c.Attr != EMPTYSTRING {
+    if value,exists := s.Attr(c.Attr); !exists || (c.Value != EMPTYSTRING && value != c.Value) {
+      return false
+    }
+  }
+
+  return c.When == nil || c.When(s)
+}
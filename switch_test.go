@@ -0,0 +1,147 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "fmt"
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+const switchHtml = `<ul>
+<li class="card" data-type="video"><a href="/v/1">Video 1</a></li>
+<li class="card sponsored"><a href="/ad">Ad</a></li>
+<li class="card" data-type="article"><h3>Article 1</h3></li>
+<li class="card" data-type="poll"><span class="question">Why?</span></li>
+<li class="card" data-type="video" data-private><a href="/v/2">Video 2</a></li>
+</ul>`
+
+func TestSwitch(t *testing.T) {
+  private := func (s *goquery.Selection) bool {
+    _,exists := s.Attr("data-private")
+    return exists
+  }
+
+  model := Switch{
+    Cases: []Case{
+      {Is: ".sponsored", Data: Skip},
+      {Attr: "data-type", Value: "video", When: private, Data: Skip},
+      {Attr: "data-type", Value: "video", Data: RetrieverModel{Sel: "a", Attr: "href"}},
+      {Has: ".question", Data: RetrieverModel{Sel: ".question", Method: "text"}},
+    },
+    Default: RetrieverModel{Sel: "h3", Method: "text"},
+  }
+
+  for _,opts := range([]ScrapeOptions{{}, {Workers: 4}}) {
+    if cards,err := ScrapeFromStringWithOptions(".card", model, switchHtml, opts); err == nil {
+      if fmt.Sprint(cards) != "[/v/1 Article 1 Why?]" {
+        t.Fatalf("invalid cards: expected %v got %v", "[/v/1 Article 1 Why?]", cards)
+      }
+    } else {
+      t.Fatal(err)
+    }
+  }
+
+  compiled, err := Compile(Model{"Card": model})
+
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if cards,err := ScrapeFromString(".card", compiled, switchHtml); err != nil || len(cards) != 3 {
+    t.Fatalf("invalid compiled cards: %v (%v)", cards, err)
+  }
+}
+
+func TestSkipNested(t *testing.T) {
+  model := Model{
+    "Title": "text",
+    "Links": RetrieverModel{Scrape: ScrapeObject{Iterator: "a", Data: Switch{
+      Cases: []Case{{Attr: "href", Value: "/ad", Data: Skip}},
+      Default: "href",
+    }}},
+  }
+
+  html := `<div><a href="/ad">Ad</a><a href="/1">1</a></div><div><a href="/2">2</a></div>`
+
+  if items,err := ScrapeFromString("div", model, html); err == nil {
+    if fmt.Sprint(items) != "[map[Links:[/1] Title:Ad1] map[Links:[/2] Title:2]]" {
+      t.Fatalf("invalid items: %v", items)
+    }
+  } else {
+    t.Fatal(err)
+  }
+
+  // A func retriever can also skip the item it is extracting.
+  skipOdd := func (s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
+    if ctx.Index % 2 == 1 {
+      return nil, ErrSkip
+    }
+
+    return ctx.Index, nil
+  }
+
+  if items,err := ScrapeFromString("li", Model{"Index": skipOdd}, numberedList(5)); err == nil {
+    if fmt.Sprint(items) != "[map[Index:0] map[Index:2] map[Index:4]]" {
+      t.Fatalf("invalid items: %v", items)
+    }
+  } else {
+    t.Fatal(err)
+  }
+
+  // Wrapped ErrSkip errors skip too, even from a FirstOf candidate or with DefaultOnError.
+  skipWrapped := func (s *goquery.Selection, ctx *ScrapeContext) (interface{}, error) {
+    if ctx.Index % 2 == 1 {
+      return nil, fmt.Errorf("odd item: %w", ErrSkip)
+    }
+
+    return ctx.Index, nil
+  }
+  model = Model{
+    "Index": FirstOf{skipWrapped, "id"},
+    "Default": RetrieverModel{Method: skipWrapped, DefaultValue: -1, DefaultOnError: true},
+  }
+
+  if items,err := ScrapeFromString("li", model, numberedList(4)); err == nil {
+    if fmt.Sprint(items) != "[map[Default:0 Index:0] map[Default:2 Index:2]]" {
+      t.Fatalf("invalid items: %v", items)
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestSkipLimit(t *testing.T) {
+  skipAds := Switch{Cases: []Case{{Is: ".ad", Data: Skip}}, Default: "text"}
+
+  // Skipped items don't count towards the limit.
+  if item,err := ScrapeOneFromString("li", skipAds, `<ul><li class="ad">ad</li><li>real</li></ul>`); err != nil || item != "real" {
+    t.Fatalf("invalid item: %v (%v)", item, err)
+  }
+
+  html := `<ul><li class="ad">ad</li><li>a</li><li class="ad">ad</li><li>b</li><li>c</li></ul>`
+
+  for _,workers := range([]int{0, 4}) {
+    if items,err := ScrapeFromStringWithOptions("li", skipAds, html, ScrapeOptions{Limit: 2, Workers: workers}); err != nil || fmt.Sprint(items) != "[a b]" {
+      t.Fatalf("invalid items with %d workers: %v (%v)", workers, items, err)
+    }
+  }
+
+  if item,err := ScrapeOneFromString("li", skipAds, `<ul><li class="ad">ad</li></ul>`); err != ErrNotFound {
+    t.Fatalf("expected ErrNotFound got %v (%v)", item, err)
+  }
+}
+
+func TestValidateSwitch(t *testing.T) {
+  err := ValidateModel(Switch{Cases: []Case{{Is: "li[", Data: "text"}, {Value: "x"}}})
+
+  if err == nil || err.Error() != `model: invalid selector "li[": expected identifier, found EOF instead; model: Cases[1] has no Data; model: Cases[1] has a Value but no Attr` {
+    t.Fatalf("invalid validation error: %v", err)
+  }
+
+  if err := ValidateModel(Switch{}); err == nil || err.Error() != "model: empty Switch" {
+    t.Fatalf("invalid validation error: %v", err)
+  }
+}
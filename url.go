@@ -0,0 +1,120 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "net/url"
+  "strings"
+  "sync"
+  "github.com/PuerkitoBio/goquery"
+)
+
+// urlAttrs is the set of attributes whose values are URLs.
+var urlAttrs = map[string]bool{
+  "action": true,
+  "background": true,
+  "cite": true,
+  "codebase": true,
+  "data": true,
+  "formaction": true,
+  "href": true,
+  "icon": true,
+  "longdesc": true,
+  "manifest": true,
+  "poster": true,
+  "src": true,
+}
+
+// scrapedDocument is the document being scraped. Its root and base URL are only determined the first
+// time they are needed, since finding the <base href> tag means searching the whole document and nested
+// scrapes rarely need either.
+type scrapedDocument struct {
+  // A selection of the document, i.e. the one being scraped. Can be nil.
+  s *goquery.Selection
+  rootOnce sync.Once
+  rootSel *goquery.Selection
+  baseOnce sync.Once
+  baseUrl *url.URL
+}
+
+// newScrapedDocument returns the document s is part of. The first <base href> tag in the document is resolved
+// against baseUrl (or docUrl if baseUrl is empty).
+func newScrapedDocument(s *goquery.Selection, docUrl *url.URL, baseUrl string) (*scrapedDocument, error) {
+  doc := &scrapedDocument{s: s, baseUrl: docUrl}
+
+  if baseUrl != EMPTYSTRING {
+    if u,err := url.Parse(baseUrl); err == nil {
+      doc.baseUrl = u
+    } else {
+      return nil, err
+    }
+  }
+
+  return doc, nil
+}
+
+// root returns a selection of the topmost ancestor of the document, or nil if there is none.
+func (doc *scrapedDocument) root() *goquery.Selection {
+  doc.rootOnce.Do(func () {
+    if doc.s == nil || len(doc.s.Nodes) == 0 {
+      return
+    }
+
+    n := doc.s.Nodes[0]
+
+    for n.Parent != nil {
+      n = n.Parent
+    }
+
+    doc.rootSel = goquery.NewDocumentFromNode(n).Selection
+  })
+
+  return doc.rootSel
+}
+
+// base returns the URL relative URLs in the document are resolved against, or nil if there is none.
+func (doc *scrapedDocument) base() *url.URL {
+  doc.baseOnce.Do(func () {
+    root := doc.root()
+
+    if root == nil {
+      return
+    }
+
+    if href,exists := root.Find("base[href]").First().Attr("href"); exists {
+      if u,err := url.Parse(strings.TrimSpace(href)); err == nil {
+        if doc.baseUrl != nil {
+          u = doc.baseUrl.ResolveReference(u)
+        }
+
+        doc.baseUrl = u
+      }
+    }
+  })
+
+  return doc.baseUrl
+}
+
+// resolveUrl resolves ref against base. If base is nil or ref is not a valid URL then ref is returned verbatim.
+func resolveUrl(base *url.URL, ref string) string {
+  if base == nil {
+    return ref
+  }
+
+  if u,err := url.Parse(strings.TrimSpace(ref)); err == nil {
+    return base.ResolveReference(u).String()
+  }
+
+  return ref
+}
+
+// resolveAttr resolves the value of a URL-valued attribute when the ResolveUrls option is set.
+func (sc *scraper) resolveAttr(attr string, value string) string {
+  if sc.opts.ResolveUrls && urlAttrs[strings.ToLower(attr)] {
+    return resolveUrl(sc.doc.base(), value)
+  }
+
+  return value
+}
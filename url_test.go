@@ -0,0 +1,100 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "io"
+  "net/http"
+  "net/http/httptest"
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+const linksHtml = `<html><body>
+<a href="/games/570"><img src="logo.jpg"></a>
+<a href="http://example.org/about"><img src="//cdn.example.org/about.jpg"></a>
+</body></html>`
+
+func TestScrapeResolveUrls(t *testing.T) {
+  if links,err := ScrapeFromStringWithOptions("a", Model{
+    "Url": "href",
+    "Logo": RetrieverModel{Sel: "img", Attr: "src"},
+  }, linksHtml, ScrapeOptions{BaseUrl: "http://example.com/store/", ResolveUrls: true}); err == nil {
+    expected := []Model{
+      Model{"Url": "http://example.com/games/570", "Logo": "http://example.com/store/logo.jpg"},
+      Model{"Url": "http://example.org/about", "Logo": "http://cdn.example.org/about.jpg"},
+    }
+
+    if len(links) != len(expected) {
+      t.Fatalf("link count invalid: expected %v got %v", len(expected), len(links))
+    }
+
+    for i,data := range(links) {
+      link := data.(Model)
+
+      if link["Url"] != expected[i]["Url"] || link["Logo"] != expected[i]["Logo"] {
+        t.Fatalf("invalid link retrieved: expected %v got %v", expected[i], link)
+      }
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestScrapeAbsUrlMethod(t *testing.T) {
+  html := `<html><head><base href="/store/"></head><body>` + linksHtml + `</body></html>`
+
+  if links,err := ScrapeFromStringWithOptions("a", Model{
+    "Url": RetrieverModel{Method: "absUrl"},
+    "Logo": RetrieverModel{Sel: "img", Attr: "src", Method: "absUrl"},
+    "RawLogo": RetrieverModel{Sel: "img", Attr: "src"},
+  }, html, ScrapeOptions{BaseUrl: "http://example.com/"}); err == nil {
+    link := links[0].(Model)
+
+    if link["Url"] != "http://example.com/games/570" {
+      t.Fatalf("invalid url retrieved: expected %v got %v", "http://example.com/games/570", link["Url"])
+    }
+    if link["Logo"] != "http://example.com/store/logo.jpg" {
+      t.Fatalf("invalid logo retrieved: expected %v got %v", "http://example.com/store/logo.jpg", link["Logo"])
+    }
+    if link["RawLogo"] != "logo.jpg" {
+      t.Fatalf("invalid raw logo retrieved: expected %v got %v", "logo.jpg", link["RawLogo"])
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func TestScrapeFromUrlResolvesAgainstFetchUrl(t *testing.T) {
+  server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+    io.WriteString(w, linksHtml)
+  }))
+  defer server.Close()
+
+  if links,err := ScrapeFromUrlWithOptions("a", "href", server.URL + "/store/", ScrapeOptions{ResolveUrls: true}); err == nil {
+    if links[0] != server.URL + "/games/570" {
+      t.Fatalf("invalid url retrieved: expected %v got %v", server.URL + "/games/570", links[0])
+    }
+  } else {
+    t.Fatal(err)
+  }
+}
+
+func BenchmarkScrapeNestedFunc(b *testing.B) {
+  doc := loadSteamFixture(b)
+  model := Model{
+    "Name": RetrieverModel{Sel: ".search_name h4", Method: "text"},
+    "Genres": func (s *goquery.Selection) (interface{}, error) {
+      return Scrape(".search_name p", "text", s, 0)
+    },
+  }
+  b.ResetTimer()
+
+  for i := 0; i < b.N; i++ {
+    if _,err := Scrape(".search_result_row", model, doc.Selection, 0); err != nil {
+      b.Fatal(err)
+    }
+  }
+}
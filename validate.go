@@ -0,0 +1,174 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "fmt"
+  "strings"
+  "github.com/andybalholm/cascadia"
+  "github.com/PuerkitoBio/goquery"
+)
+
+// ModelError describes a single problem found in a data model.
+type ModelError struct {
+  // Where the problem is in the model tree, i.e. "Comments.Scrape.Data.Author". Empty for the root model.
+  Path string
+  Message string
+}
+
+func (e ModelError) Error() string {
+  return displayPath(e.Path) + ": " + e.Message
+}
+
+// ValidationError lists every problem found in a data model by ValidateModel.
+type ValidationError []ModelError
+
+func (e ValidationError) Error() string {
+  messages := make([]string, len(e))
+
+  for i,problem := range(e) {
+    messages[i] = problem.Error()
+  }
+
+  return strings.Join(messages, "; ")
+}
+
+/*
+ValidateModel statically checks a data model and every data model nested in it, including the data
+models of recursive scrapes, without scraping any HTML. Returns a ValidationError listing every problem
+found along with its path, or nil if the model is valid.
+
+Problems reported include unsupported Go types, invalid CSS selectors, unrecognized methods, empty
+RetrieverModels, RetrieverModels whose settings would be ignored (i.e. a Sel without a Method, Attr or
+Scrape to extract with) and Computed used anywhere other than as a Model or OrderedModel value.
+
+Example:
+
+    if err := dtoo.ValidateModel(model); err != nil {
+      for _,problem := range(err.(dtoo.ValidationError)) {
+        log.Println(problem.Path, problem.Message)
+      }
+    }
+*/
+func ValidateModel(model interface{}) error {
+  problems := ValidationError{}
+  report := func (path string, format string, args ...interface{}) {
+    problems = append(problems, ModelError{Path: path, Message: fmt.Sprintf(format, args...)})
+  }
+  // The paths of the Model and OrderedModel values, the only places a Computed can be used.
+  fields := map[string]bool{}
+
+  walkModel(model, EMPTYSTRING, func (path string, model interface{}) {
+    for _,sel := range(modelSelectors(model)) {
+      if sel != EMPTYSTRING {
+        if _,err := cascadia.Compile(sel); err != nil {
+          report(path, "invalid selector %q: %v", sel, err)
+        }
+      }
+    }
+
+    switch modelValue := model.(type) {
+      case string:
+        if modelValue == EMPTYSTRING {
+          report(path, "empty string data model")
+        }
+      case Model:
+        for key := range(modelValue) {
+          fields[joinPath(path, key)] = true
+        }
+
+        if _,err := computedOrder(sortedKeys(modelValue), func (key string) (value interface{}, exists bool) {
+          value, exists = modelValue[key]
+          return
+        }); err != nil {
+          report(path, "%v", err)
+        }
+      case OrderedModel:
+        for _,field := range(modelValue) {
+          fields[joinPath(path, field.Key)] = true
+        }
+
+        if _,err := computedOrder(modelValue.Keys(), modelValue.Get); err != nil {
+          report(path, "%v", err)
+        }
+      case FirstOf:
+        if len(modelValue) == 0 {
+          report(path, "empty FirstOf")
+        }
+      case Switch:
+        if len(modelValue.Cases) == 0 && modelValue.Default == nil {
+          report(path, "empty Switch")
+        }
+
+        for i,c := range(modelValue.Cases) {
+          if c.Data == nil {
+            report(path, "Cases[%d] has no Data", i)
+          }
+          if c.Value != EMPTYSTRING && c.Attr == EMPTYSTRING {
+            report(path, "Cases[%d] has a Value but no Attr", i)
+          }
+        }
+      case skipModel:
+      case Computed:
+        if !fields[path] {
+          report(path, "Computed can only be used as a Model or OrderedModel value")
+        } else if modelValue.Func == nil {
+          report(path, "Computed has no Func")
+        }
+      case *CompiledModel:
+        // The compiled model is scraped on its own, so its root isn't a Model or OrderedModel value.
+        delete(fields, path)
+      case func (s *goquery.Selection) (interface{}, error),
+        func (s *goquery.Selection, ctx *ScrapeContext) (interface{}, error):
+      case RetrieverModel:
+        validateRetrieverModel(path, modelValue, report)
+      default:
+        report(path, "unsupported retriever type %T", model)
+    }
+  })
+
+  if len(problems) > 0 {
+    return problems
+  }
+
+  return nil
+}
+
+func validateRetrieverModel(path string, rm RetrieverModel, report func (path string, format string, args ...interface{})) {
+  hasScrape := rm.Scrape.Iterator != EMPTYSTRING || rm.Scrape.Data != nil
+
+  if rm.Sel == EMPTYSTRING && rm.Attr == EMPTYSTRING && rm.Method == nil && !hasScrape {
+    report(path, "empty RetrieverModel")
+    return
+  }
+
+  if rm.Sel != EMPTYSTRING && rm.Attr == EMPTYSTRING && rm.Method == nil && !hasScrape {
+    report(path, "Sel %q has no Method, Attr or Scrape to extract with", rm.Sel)
+  }
+
+  switch method := rm.Method.(type) {
+    case nil, func (s *goquery.Selection) (interface{}, error), func (s *goquery.Selection, ctx *ScrapeContext) (interface{}, error):
+    case string:
+      if !isMethodName(method) {
+        report(path, "unrecognized method %q", method)
+      } else if rm.Attr != EMPTYSTRING && method != "absUrl" {
+        report(path, "method %q is ignored when Attr is set", method)
+      }
+    default:
+      report(path, "unrecognized method type %T", rm.Method)
+  }
+
+  if hasScrape {
+    if rm.Scrape.Iterator == EMPTYSTRING {
+      report(path, "Scrape has no Iterator")
+    }
+    if rm.Scrape.Data == nil {
+      report(path, "Scrape has no Data")
+    }
+    if rm.Attr != EMPTYSTRING || rm.Method != nil {
+      report(path, "Scrape is ignored when Attr or Method is set")
+    }
+  }
+}
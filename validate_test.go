@@ -0,0 +1,62 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "testing"
+  "github.com/PuerkitoBio/goquery"
+)
+
+func TestValidateModelAcceptsValidModels(t *testing.T) {
+  if err := ValidateModel(Model{
+    "Id": "id",
+    "Title": RetrieverModel{Sel: ".post-title", Method: "text"},
+    "Permalink": RetrieverModel{Sel: "a", Attr: "href", Method: "absUrl"},
+    "Summary": func (s *goquery.Selection) (interface{}, error) { return s.Text(), nil },
+    "Comments": RetrieverModel{Scrape: ScrapeObject{Iterator: ".comment", Data: OrderedModel{
+      {Key: "Author", Value: RetrieverModel{Sel: ".comment-author", Method: "text"}},
+    }}},
+  }); err != nil {
+    t.Fatal(err)
+  }
+}
+
+func TestValidateModelReportsAllProblems(t *testing.T) {
+  err := ValidateModel(Model{
+    "Count": 3,
+    "Title": RetrieverModel{Sel: ".post-title"},
+    "Date": RetrieverModel{Sel: "time[", Method: "text"},
+    "Comments": RetrieverModel{Scrape: ScrapeObject{Iterator: ".comment", Data: Model{
+      "Author": RetrieverModel{Sel: ".comment-author", Method: "txt"},
+      "Likes": nil,
+    }}},
+    "Tags": RetrieverModel{Scrape: ScrapeObject{Iterator: ".tag"}},
+  })
+
+  problems, ok := err.(ValidationError)
+
+  if !ok {
+    t.Fatalf("invalid error: expected a ValidationError got %v", err)
+  }
+
+  expected := []string{
+    `Comments.Scrape.Data.Author: unrecognized method "txt"`,
+    `Comments.Scrape.Data.Likes: unsupported retriever type <nil>`,
+    `Count: unsupported retriever type int`,
+    `Date: invalid selector "time[": expected identifier, found EOF instead`,
+    `Tags: Scrape has no Data`,
+    `Title: Sel ".post-title" has no Method, Attr or Scrape to extract with`,
+  }
+
+  if len(problems) != len(expected) {
+    t.Fatalf("problem count invalid: expected %v got %v (%v)", len(expected), len(problems), problems)
+  }
+
+  for i,problem := range(problems) {
+    if problem.Error() != expected[i] {
+      t.Fatalf("invalid problem: expected %v got %v", expected[i], problem.Error())
+    }
+  }
+}
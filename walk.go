@@ -0,0 +1,54 @@
+// Copyright 2014 Darren Schnare. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package dtoo
+
+import (
+  "strconv"
+)
+
+// walkModel calls visit for model and then for every data model nested in it, depth first.
+// path describes where model is in the tree, i.e. "Comments.Scrape.Data.Author".
+func walkModel(model interface{}, path string, visit func (path string, model interface{})) {
+  visit(path, model)
+
+  switch modelValue := model.(type) {
+    case Model:
+      for _,key := range(sortedKeys(modelValue)) {
+        walkModel(modelValue[key], joinPath(path, key), visit)
+      }
+    case OrderedModel:
+      for _,field := range(modelValue) {
+        walkModel(field.Value, joinPath(path, field.Key), visit)
+      }
+    case FirstOf:
+      for i,candidate := range(modelValue) {
+        walkModel(candidate, joinPath(path, "FirstOf[" + strconv.Itoa(i) + "]"), visit)
+      }
+    case Switch:
+      for i,c := range(modelValue.Cases) {
+        if c.Data != nil {
+          walkModel(c.Data, joinPath(path, "Cases[" + strconv.Itoa(i) + "].Data"), visit)
+        }
+      }
+
+      if modelValue.Default != nil {
+        walkModel(modelValue.Default, joinPath(path, "Default"), visit)
+      }
+    case RetrieverModel:
+      if modelValue.Scrape.Data != nil {
+        walkModel(modelValue.Scrape.Data, joinPath(path, "Scrape.Data"), visit)
+      }
+    case *CompiledModel:
+      walkModel(modelValue.model, path, visit)
+  }
+}
+
+func joinPath(path string, key string) string {
+  if path == EMPTYSTRING {
+    return key
+  }
+
+  return path + "." + key
+}